@@ -0,0 +1,159 @@
+package app
+
+import (
+	"crypto/md5"
+	"encoding/hex"
+	"encoding/json"
+	"net/url"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/osu-datenshi/api/common"
+	"github.com/valyala/fasthttp"
+)
+
+// CacheRule describes how the responses of a method are cached.
+type CacheRule struct {
+	TTL time.Duration
+	// UserScoped methods return an user (with its id at the top level of
+	// the response), and their cache is dropped when the user submits a
+	// score.
+	UserScoped bool
+}
+
+// cacheControl returns the Cache-Control header of the responses. The
+// responses of UserScoped methods can change before their TTL, when their
+// cache is dropped, so clients have to revalidate them every time through
+// their ETag.
+func (r CacheRule) cacheControl() string {
+	if r.UserScoped {
+		return "no-cache"
+	}
+	return "public, max-age=" + strconv.Itoa(int(r.TTL/time.Second))
+}
+
+// cacheIgnoredArgs are the querystring parameters that don't change the
+// resource that is returned.
+var cacheIgnoredArgs = map[string]bool{
+	"token":    true,
+	"k":        true,
+	"callback": true,
+	"pls200":   true,
+}
+
+// cacheKey generates the key in redis of the cached response to a request,
+// so that the same request with the parameters in a different order still
+// hits the cache.
+func cacheKey(c *fasthttp.RequestCtx) string {
+	var args [][2]string
+	c.QueryArgs().VisitAll(func(k, v []byte) {
+		if !cacheIgnoredArgs[string(k)] {
+			args = append(args, [2]string{string(k), string(v)})
+		}
+	})
+	sort.Slice(args, func(i, j int) bool {
+		if args[i][0] == args[j][0] {
+			return args[i][1] < args[j][1]
+		}
+		return args[i][0] < args[j][0]
+	})
+	q := make(url.Values, len(args))
+	for _, a := range args {
+		q[a[0]] = append(q[a[0]], a[1])
+	}
+	return "api:cache:" + string(c.Path()) + "?" + q.Encode()
+}
+
+// cacheUserKey is the set holding the cached responses about an user.
+func cacheUserKey(userID int) string {
+	return "api:cache:user:" + strconv.Itoa(userID)
+}
+
+// cacheable returns whether the response to the request can be shared with
+// everyone else, that is, it does not contain anything the client can see
+// only because of who they are.
+func cacheable(md common.MethodData) bool {
+	if md.Query("id") == "self" {
+		return false
+	}
+	if md.User.ID == 0 {
+		return true
+	}
+	return md.User.UserPrivileges&common.UserPrivilegePublic != 0 &&
+		md.User.UserPrivileges&common.AdminPrivilegeManageUsers == 0 &&
+		md.User.TokenPrivileges&common.PrivilegeManageUser == 0
+}
+
+// cachedResponse is a successful response, already encoded in JSON.
+type cachedResponse []byte
+
+func (r cachedResponse) GetCode() int                 { return 200 }
+func (r cachedResponse) GetMessage() string           { return "" }
+func (r cachedResponse) MarshalJSON() ([]byte, error) { return r, nil }
+
+// cached wraps f so that its successful responses are stored in redis and
+// served from there until they expire. Responses come with an ETag, and if
+// the client already has the response a 304 is returned.
+func cached(f func(md common.MethodData) common.CodeMessager, rule CacheRule) func(md common.MethodData) common.CodeMessager {
+	return func(md common.MethodData) common.CodeMessager {
+		if !cacheable(md) {
+			return f(md)
+		}
+		key := cacheKey(md.Ctx)
+
+		body, err := md.R.Get(key).Bytes()
+		if err != nil {
+			resp := f(md)
+			if resp.GetCode() != 200 {
+				return resp
+			}
+			body, err = json.MarshalIndent(resp, "", "\t")
+			if err != nil {
+				md.Err(err)
+				return resp
+			}
+			storeCached(md, key, body, rule)
+		}
+
+		sum := md5.Sum(body)
+		etag := `"` + hex.EncodeToString(sum[:]) + `"`
+		md.Ctx.Response.Header.Set("ETag", etag)
+		md.Ctx.Response.Header.Set("Cache-Control", rule.cacheControl())
+		if string(md.Ctx.Request.Header.Peek("If-None-Match")) == etag {
+			return common.RawResponse{ResponseBase: common.ResponseBase{Code: 304}}
+		}
+		return cachedResponse(body)
+	}
+}
+
+func storeCached(md common.MethodData, key string, body []byte, rule CacheRule) {
+	err := md.R.Set(key, body, rule.TTL).Err()
+	if err != nil {
+		md.Err(err)
+		return
+	}
+	if !rule.UserScoped {
+		return
+	}
+	var u struct {
+		ID int `json:"id"`
+	}
+	if json.Unmarshal(body, &u) != nil || u.ID == 0 {
+		return
+	}
+	userKey := cacheUserKey(u.ID)
+	md.R.SAdd(userKey, key)
+	md.R.Expire(userKey, rule.TTL)
+}
+
+// dropUserCache removes all the cached responses about an user.
+func dropUserCache(s submittedScore) {
+	userKey := cacheUserKey(s.UserID)
+	keys, err := red.SMembers(userKey).Result()
+	if err != nil {
+		common.GenericError(err)
+		return
+	}
+	red.Del(append(keys, userKey)...)
+}
@@ -0,0 +1,67 @@
+package app
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/osu-datenshi/api/common"
+	"github.com/valyala/fasthttp"
+)
+
+func Test_cacheKey(t *testing.T) {
+	tests := []struct {
+		name string
+		uri  string
+		want string
+	}{
+		{"no query", "/api/v1/badges", "api:cache:/api/v1/badges?"},
+		{"sorted", "/api/v1/leaderboard?p=2&mode=taiko", "api:cache:/api/v1/leaderboard?mode=taiko&p=2"},
+		{"token ignored", "/api/v1/users/full?token=abc&id=1000&k=def", "api:cache:/api/v1/users/full?id=1000"},
+		{"callback ignored", "/api/v1/badges?callback=cb&pls200", "api:cache:/api/v1/badges?"},
+		{"repeated", "/api/v1/beatmaps?s=2&s=1", "api:cache:/api/v1/beatmaps?s=1&s=2"},
+	}
+	for _, tt := range tests {
+		var c fasthttp.RequestCtx
+		c.Request.SetRequestURI(tt.uri)
+		if got := cacheKey(&c); got != tt.want {
+			t.Errorf("%q. cacheKey() = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func Test_cachedResponse(t *testing.T) {
+	resp := struct {
+		common.ResponseBase
+		Users []string `json:"users"`
+	}{common.ResponseBase{Code: 200}, []string{"a", "b"}}
+	body, err := json.MarshalIndent(resp, "", "\t")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// a cached response must be sent exactly as it was first encoded, for
+	// the ETag to stay the same.
+	got, err := json.MarshalIndent(cachedResponse(body), "", "\t")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(got) != string(body) {
+		t.Errorf("cachedResponse encoded as %s, want %s", got, body)
+	}
+}
+
+func TestCacheRule_cacheControl(t *testing.T) {
+	tests := []struct {
+		name string
+		rule CacheRule
+		want string
+	}{
+		{"public", CacheRule{TTL: 5 * time.Minute}, "public, max-age=300"},
+		{"user scoped", CacheRule{TTL: 5 * time.Minute, UserScoped: true}, "no-cache"},
+	}
+	for _, tt := range tests {
+		if got := tt.rule.cacheControl(); got != tt.want {
+			t.Errorf("%q. cacheControl() = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
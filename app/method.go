@@ -19,7 +19,102 @@ func Method(f func(md common.MethodData) common.CodeMessager, privilegesNeeded .
 	}
 }
 
+// CachedMethod is like Method, but the responses are cached as described by
+// rule.
+func CachedMethod(f func(md common.MethodData) common.CodeMessager, rule CacheRule, privilegesNeeded ...int) fasthttp.RequestHandler {
+	return Method(cached(f, rule), privilegesNeeded...)
+}
+
 func initialCaretaker(c *fasthttp.RequestCtx, f func(md common.MethodData) common.CodeMessager, privilegesNeeded ...int) {
+	md, doggoTags := methodData(c)
+
+	doggo.Incr("requests.v1", doggoTags, 1)
+
+	if !methodAllowed(md) {
+		doggo.Incr("requests.v1.limited", doggoTags, 1)
+		c.SetStatusCode(429)
+		c.Response.Header.SetContentType("application/json; charset=utf-8")
+		mkjson(c, common.SimpleResponse(429, "Too many requests, slow down."))
+		return
+	}
+
+	missing := missingPrivileges(md, privilegesNeeded)
+	if missing != 0 {
+		c.SetStatusCode(401)
+		mkjson(c, common.SimpleResponse(401, "You don't have the privilege(s): "+common.Privileges(missing).String()+"."))
+		return
+	}
+
+	resp := f(md)
+	if raw, ok := resp.(common.RawResponse); ok {
+		writeRaw(c, raw)
+		return
+	}
+	if md.HasQuery("pls200") {
+		c.SetStatusCode(200)
+	} else {
+		c.SetStatusCode(resp.GetCode())
+	}
+
+	if md.HasQuery("callback") {
+		c.Response.Header.SetContentType("application/javascript; charset=utf-8")
+	} else {
+		c.Response.Header.SetContentType("application/json; charset=utf-8")
+	}
+
+	mkjson(c, resp)
+}
+
+// writeRaw writes a RawResponse to the client.
+func writeRaw(c *fasthttp.RequestCtx, raw common.RawResponse) {
+	c.SetStatusCode(raw.GetCode())
+	if raw.ContentType != "" {
+		c.Response.Header.SetContentType(raw.ContentType)
+	}
+	if raw.Filename != "" {
+		c.Response.Header.Set("Content-Disposition", contentDisposition(raw.Filename))
+	}
+	c.Write(raw.Body)
+}
+
+// contentDisposition returns the Content-Disposition header asking to download
+// a file. As header values can only be ASCII, the name is given both in
+// filename, with the other characters replaced by underscores, and in full
+// in filename*, percent-encoded as described by RFC 5987.
+func contentDisposition(filename string) string {
+	ascii := make([]byte, 0, len(filename))
+	encoded := make([]byte, 0, len(filename))
+	for _, r := range filename {
+		if r >= 0x20 && r < 0x7f && r != '"' && r != '\\' {
+			ascii = append(ascii, byte(r))
+		} else {
+			ascii = append(ascii, '_')
+		}
+	}
+	for i := 0; i < len(filename); i++ {
+		b := filename[i]
+		if isAttrChar(b) {
+			encoded = append(encoded, b)
+		} else {
+			encoded = append(encoded, '%', "0123456789ABCDEF"[b>>4], "0123456789ABCDEF"[b&15])
+		}
+	}
+	return `attachment; filename="` + string(ascii) + `"; filename*=UTF-8''` + string(encoded)
+}
+
+// isAttrChar returns whether b is an attr-char of RFC 5987, which doesn't
+// need to be percent-encoded.
+func isAttrChar(b byte) bool {
+	switch {
+	case b >= 'a' && b <= 'z', b >= 'A' && b <= 'Z', b >= '0' && b <= '9':
+		return true
+	}
+	return strings.IndexByte("!#$&+-.^_`|~", b) != -1
+}
+
+// methodData builds the MethodData for a request, authenticating the user if
+// they passed a token. It also returns the tags to be sent to datadog.
+func methodData(c *fasthttp.RequestCtx) (common.MethodData, []string) {
 	var doggoTags []string
 
 	qa := c.Request.URI().QueryArgs()
@@ -62,38 +157,28 @@ func initialCaretaker(c *fasthttp.RequestCtx, f func(md common.MethodData) commo
 	}
 
 	// log into datadog that this is an hanayo request
-	if b2s(c.Request.Header.Peek("H-Key")) == cf.HanayoKey && b2s(c.UserAgent()) == "hanayo" {
+	if isHanayo(c) {
 		doggoTags = append(doggoTags, "hanayo")
 	}
 
-	doggo.Incr("requests.v1", doggoTags, 1)
+	return md, doggoTags
+}
+
+// isHanayo returns whether the request comes from hanayo, the frontend.
+func isHanayo(c *fasthttp.RequestCtx) bool {
+	return b2s(c.Request.Header.Peek("H-Key")) == cf.HanayoKey && b2s(c.UserAgent()) == "hanayo"
+}
 
-	missingPrivileges := 0
+// missingPrivileges returns the privileges in privilegesNeeded that the
+// token of the request does not have.
+func missingPrivileges(md common.MethodData, privilegesNeeded []int) int {
+	missing := 0
 	for _, privilege := range privilegesNeeded {
 		if uint64(md.User.TokenPrivileges)&uint64(privilege) == 0 {
-			missingPrivileges |= privilege
+			missing |= privilege
 		}
 	}
-	if missingPrivileges != 0 {
-		c.SetStatusCode(401)
-		mkjson(c, common.SimpleResponse(401, "You don't have the privilege(s): "+common.Privileges(missingPrivileges).String()+"."))
-		return
-	}
-
-	resp := f(md)
-	if md.HasQuery("pls200") {
-		c.SetStatusCode(200)
-	} else {
-		c.SetStatusCode(resp.GetCode())
-	}
-
-	if md.HasQuery("callback") {
-		c.Response.Header.SetContentType("application/javascript; charset=utf-8")
-	} else {
-		c.Response.Header.SetContentType("application/json; charset=utf-8")
-	}
-
-	mkjson(c, resp)
+	return missing
 }
 
 // Very restrictive, but this way it shouldn't completely fuck up.
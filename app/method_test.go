@@ -0,0 +1,20 @@
+package app
+
+import "testing"
+
+func Test_contentDisposition(t *testing.T) {
+	tests := []struct {
+		name     string
+		filename string
+		want     string
+	}{
+		{"ascii", "User - Song [Hard] (2020-01-02).osr", `attachment; filename="User - Song [Hard] (2020-01-02).osr"; filename*=UTF-8''User%20-%20Song%20%5BHard%5D%20%282020-01-02%29.osr`},
+		{"unicode", "ユーザー é.osr", `attachment; filename="____ _.osr"; filename*=UTF-8''%E3%83%A6%E3%83%BC%E3%82%B6%E3%83%BC%20%C3%A9.osr`},
+		{"quotes", `a"b\c;.osr`, `attachment; filename="a_b_c;.osr"; filename*=UTF-8''a%22b%5Cc%3B.osr`},
+	}
+	for _, tt := range tests {
+		if got := contentDisposition(tt.filename); got != tt.want {
+			t.Errorf("%q. contentDisposition() = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
@@ -1,8 +1,10 @@
 package peppy
 
 import (
+	"database/sql"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/jmoiron/sqlx"
 	"github.com/thehowl/go-osuapi"
@@ -15,8 +17,15 @@ func GetBeatmap(c *fasthttp.RequestCtx, db *sqlx.DB) {
 	var whereClauses []string
 	var params []interface{}
 	limit := strconv.Itoa(common.InString(1, query(c, "limit"), 500, 500))
+	orderBy := "id DESC"
 
-	// since value is not stored, silently ignore
+	if since, ok := parseSince(query(c, "since")); ok {
+		// Like on bancho, beatmaps are sorted by their ranked date. For
+		// the beatmaps that were never ranked, latest_update is used.
+		whereClauses = append(whereClauses, "IF(beatmaps.approved_date != 0, beatmaps.approved_date, beatmaps.latest_update) > ?")
+		params = append(params, since.Unix())
+		orderBy = "IF(beatmaps.approved_date != 0, beatmaps.approved_date, beatmaps.latest_update) ASC"
+	}
 	if query(c, "s") != "" {
 		whereClauses = append(whereClauses, "beatmaps.beatmapset_id = ?")
 		params = append(params, query(c, "s"))
@@ -27,20 +36,27 @@ func GetBeatmap(c *fasthttp.RequestCtx, db *sqlx.DB) {
 		// b is unique, so change limit to 1
 		limit = "1"
 	}
-	// creator is not stored, silently ignore u and type
+	// Only the name of the creator is stored, so the user ID can't be
+	// looked up, and u is always matched against the name.
+	if query(c, "u") != "" {
+		whereClauses = append(whereClauses, "beatmaps.creator = ?")
+		params = append(params, query(c, "u"))
+	}
+	// returnModes is the list of modes in which each beatmap is returned.
+	// nil means that only the original mode of the beatmap is returned.
+	var returnModes []osuapi.Mode
+	converted := query(c, "a") == "1"
 	if query(c, "m") != "" {
-		m := genmode(query(c, "m"))
-		if m == "std" {
-			// Since STD beatmaps are converted, all of the diffs must be != 0
-			for _, i := range modes {
-				whereClauses = append(whereClauses, "beatmaps.difficulty_"+i+" != 0")
-			}
-		} else {
-			whereClauses = append(whereClauses, "beatmaps.difficulty_"+m+" != 0")
-			if query(c, "a") == "1" {
-				whereClauses = append(whereClauses, "beatmaps.difficulty_std = 0")
-			}
+		m := genmodei(query(c, "m"))
+		returnModes = []osuapi.Mode{osuapi.Mode(m)}
+		// Beatmaps have a difficulty in a mode if they are either for that
+		// mode, or if they are osu!standard beatmaps and can be converted.
+		whereClauses = append(whereClauses, "beatmaps.difficulty_"+genmode(query(c, "m"))+" != 0")
+		if m != 0 && !converted {
+			whereClauses = append(whereClauses, "beatmaps.difficulty_std = 0")
 		}
+	} else if converted {
+		returnModes = []osuapi.Mode{osuapi.ModeOsu, osuapi.ModeTaiko, osuapi.ModeCatchTheBeat, osuapi.ModeOsuMania}
 	}
 	if query(c, "h") != "" {
 		whereClauses = append(whereClauses, "beatmaps.beatmap_md5 = ?")
@@ -56,9 +72,9 @@ func GetBeatmap(c *fasthttp.RequestCtx, db *sqlx.DB) {
 	beatmapset_id, beatmap_id, ranked, hit_length,
 	song_name, beatmap_md5, ar, od, bpm, playcount,
 	passcount, max_combo, difficulty_std, difficulty_taiko, difficulty_ctb, difficulty_mania,
-	latest_update
+	latest_update, approved_date, creator, tags, genre_id, language_id, source
 
-FROM beatmaps `+where+" ORDER BY id DESC LIMIT "+limit,
+FROM beatmaps `+where+" ORDER BY "+orderBy+" LIMIT "+limit,
 		params...)
 	if err != nil {
 		common.Err(c, err)
@@ -73,39 +89,82 @@ FROM beatmaps `+where+" ORDER BY id DESC LIMIT "+limit,
 			rawRankedStatus int
 			rawName         string
 			rawLastUpdate   common.UnixTimestamp
+			rawApprovedDate int64
+			genre           int
+			language        int
+			tags            sql.NullString
 			diffs           [4]float64
 		)
 		err := rows.Scan(
 			&bm.BeatmapSetID, &bm.BeatmapID, &rawRankedStatus, &bm.HitLength,
 			&rawName, &bm.FileMD5, &bm.ApproachRate, &bm.OverallDifficulty, &bm.BPM, &bm.Playcount,
 			&bm.Passcount, &bm.MaxCombo, &diffs[0], &diffs[1], &diffs[2], &diffs[3],
-			&rawLastUpdate,
+			&rawLastUpdate, &rawApprovedDate, &bm.Creator, &tags, &genre, &language, &bm.Source,
 		)
 		if err != nil {
 			common.Err(c, err)
 			continue
 		}
 		bm.TotalLength = bm.HitLength
+		bm.Tags = tags.String
 		bm.LastUpdate = osuapi.MySQLDate(rawLastUpdate)
-		if rawRankedStatus >= 2 {
+		switch {
+		case rawApprovedDate != 0:
+			bm.ApprovedDate = osuapi.MySQLDate(time.Unix(rawApprovedDate, 0))
+		case rawRankedStatus >= 2:
 			bm.ApprovedDate = osuapi.MySQLDate(rawLastUpdate)
 		}
+		bm.Genre = osuapi.Genre(genre)
+		bm.Language = osuapi.Language(language)
 		// zero value of ApprovedStatus == osuapi.StatusPending, so /shrug
 		bm.Approved = rippleToOsuRankedStatus[rawRankedStatus]
 		bm.Artist, bm.Title, bm.DiffName = parseDiffName(rawName)
-		for i, diffVal := range diffs {
-			if diffVal != 0 {
-				bm.Mode = osuapi.Mode(i)
-				bm.DifficultyRating = diffVal
-				break
+
+		if returnModes == nil {
+			bm.Mode = originalMode(diffs)
+			bm.DifficultyRating = diffs[bm.Mode]
+			bms = append(bms, bm)
+			continue
+		}
+		for _, m := range returnModes {
+			if diffs[m] == 0 {
+				continue
 			}
+			bm.Mode = m
+			bm.DifficultyRating = diffs[m]
+			bms = append(bms, bm)
 		}
-		bms = append(bms, bm)
 	}
 
 	json(c, 200, bms)
 }
 
+// originalMode returns the mode a beatmap was made for, knowing its
+// difficulties. osu!standard beatmaps have a difficulty in all the modes they
+// can be converted to, so they are recognised by having one in osu!standard.
+func originalMode(diffs [4]float64) osuapi.Mode {
+	if diffs[0] != 0 {
+		return osuapi.ModeOsu
+	}
+	for i, diff := range diffs {
+		if diff != 0 {
+			return osuapi.Mode(i)
+		}
+	}
+	return osuapi.ModeOsu
+}
+
+// parseSince parses the since parameter, which is a MySQL date in UTC.
+func parseSince(s string) (time.Time, bool) {
+	for _, layout := range []string{"2006-01-02 15:04:05", "2006-01-02"} {
+		t, err := time.Parse(layout, s)
+		if err == nil {
+			return t, true
+		}
+	}
+	return time.Time{}, false
+}
+
 var rippleToOsuRankedStatus = map[int]osuapi.ApprovedStatus{
 	0: osuapi.StatusPending,
 	1: osuapi.StatusWIP, // it means "needs updating", as the one in the db needs to be updated, but whatever
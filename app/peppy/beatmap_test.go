@@ -0,0 +1,218 @@
+package peppy
+
+import (
+	"database/sql/driver"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/osu-datenshi/api/internal/fakesql"
+)
+
+var beatmapSchema = []string{
+	`CREATE TABLE beatmaps (
+		id INT NOT NULL PRIMARY KEY,
+		beatmap_id INT NOT NULL,
+		beatmapset_id INT NOT NULL,
+		beatmap_md5 VARCHAR(32) NOT NULL,
+		song_name VARCHAR(256) NOT NULL,
+		ar FLOAT NOT NULL,
+		od FLOAT NOT NULL,
+		difficulty_std DOUBLE NOT NULL,
+		difficulty_taiko DOUBLE NOT NULL,
+		difficulty_ctb DOUBLE NOT NULL,
+		difficulty_mania DOUBLE NOT NULL,
+		max_combo INT NOT NULL,
+		hit_length INT NOT NULL,
+		bpm INT NOT NULL,
+		ranked TINYINT NOT NULL,
+		latest_update INT NOT NULL,
+		playcount INT NOT NULL,
+		passcount INT NOT NULL,
+		creator VARCHAR(32) NOT NULL,
+		tags TEXT NULL,
+		genre_id TINYINT NOT NULL,
+		language_id TINYINT NOT NULL,
+		source VARCHAR(255) NOT NULL,
+		approved_date INT NOT NULL
+	)`,
+	`INSERT INTO beatmaps VALUES
+		(1, 75, 1, 'a5b99395a42bd55bc5eb1d2411cbdf8b', 'Kenji Ninuma - DISCOPRINCE [Normal]',
+			6, 6, 2.4, 2.9, 2.3, 2.6, 314, 142, 120, 2, 1500000000, 10, 5,
+			'peppy', 'katamari', 2, 3, '', 1191692791),
+		(2, 1, 2, 'b0b1e7a9e8f4d5c6a7b8c9d0e1f2a3b4', 'Artist - Taiko Song [Oni]',
+			0, 5, 0, 4.5, 0, 0, 500, 100, 180, 2, 1500000000, 0, 0,
+			'Kotori', '', 0, 0, '', 1400000000),
+		(3, 2, 3, 'c0b1e7a9e8f4d5c6a7b8c9d0e1f2a3b4', 'Artist - Mania Song [4K]',
+			0, 8, 0, 0, 0, 3.3, 900, 120, 200, 0, 1600000000, 0, 0,
+			'Kotori', '', 0, 0, '', 0)`,
+}
+
+func TestGetBeatmap(t *testing.T) {
+	db := testDB(t, beatmapSchema...)
+	defer db.Close()
+
+	type beatmap struct {
+		BeatmapID  string `json:"beatmap_id"`
+		Mode       string `json:"mode"`
+		Creator    string `json:"creator"`
+		Difficulty string `json:"difficultyrating"`
+	}
+	tests := []struct {
+		name string
+		uri  string
+		want []beatmap
+	}{
+		{
+			"original modes",
+			"/api/get_beatmaps",
+			[]beatmap{
+				{"2", "3", "Kotori", "3.3"},
+				{"1", "1", "Kotori", "4.5"},
+				{"75", "0", "peppy", "2.4"},
+			},
+		},
+		{
+			"taiko",
+			"/api/get_beatmaps?m=1",
+			[]beatmap{
+				{"1", "1", "Kotori", "4.5"},
+			},
+		},
+		{
+			"taiko with converts",
+			"/api/get_beatmaps?m=1&a=1",
+			[]beatmap{
+				{"1", "1", "Kotori", "4.5"},
+				{"75", "1", "peppy", "2.9"},
+			},
+		},
+		{
+			"all converts",
+			"/api/get_beatmaps?b=75&a=1",
+			[]beatmap{
+				{"75", "0", "peppy", "2.4"},
+				{"75", "1", "peppy", "2.9"},
+				{"75", "2", "peppy", "2.3"},
+				{"75", "3", "peppy", "2.6"},
+			},
+		},
+		{
+			"creator",
+			"/api/get_beatmaps?u=peppy&type=string",
+			[]beatmap{
+				{"75", "0", "peppy", "2.4"},
+			},
+		},
+		{
+			"since",
+			"/api/get_beatmaps?since=2010-01-01",
+			[]beatmap{
+				{"1", "1", "Kotori", "4.5"},
+				{"2", "3", "Kotori", "3.3"},
+			},
+		},
+	}
+	for _, tt := range tests {
+		got := []beatmap{}
+		testRequest(t, db, GetBeatmap, tt.uri, &got)
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%q. GetBeatmap() = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestGetBeatmapQuery(t *testing.T) {
+	i := func(x int64) driver.Value { return x }
+	row := func(id int64, diffs ...float64) []driver.Value {
+		// tags is NULL, as it is for the beatmaps inserted by the servers
+		// which don't know about it.
+		return []driver.Value{i(1), i(id), i(2), i(100), "Artist - Title [Diff]", "md5",
+			5.0, 5.0, i(180), i(0), i(0), i(300), diffs[0], diffs[1], diffs[2], diffs[3],
+			i(1500000000), i(0), "peppy", nil, i(0), i(0), ""}
+	}
+	db, queries := fakesql.Open(fakesql.Result{
+		Match: "FROM beatmaps",
+		Columns: []string{
+			"beatmapset_id", "beatmap_id", "ranked", "hit_length", "song_name", "beatmap_md5",
+			"ar", "od", "bpm", "playcount", "passcount", "max_combo", "difficulty_std",
+			"difficulty_taiko", "difficulty_ctb", "difficulty_mania", "latest_update",
+			"approved_date", "creator", "tags", "genre_id", "language_id", "source",
+		},
+		Rows: [][]driver.Value{
+			row(75, 2.4, 2.9, 2.3, 2.6),
+			row(1, 0, 4.5, 0, 0),
+		},
+	})
+	defer db.Close()
+
+	type beatmap struct {
+		BeatmapID  string `json:"beatmap_id"`
+		Mode       string `json:"mode"`
+		Difficulty string `json:"difficultyrating"`
+		Tags       string `json:"tags"`
+		Approved   string `json:"approved_date"`
+	}
+	tests := []struct {
+		name  string
+		uri   string
+		want  []beatmap
+		query []string
+		args  []driver.Value
+	}{
+		{
+			"original modes",
+			"/api/get_beatmaps",
+			[]beatmap{
+				{"75", "0", "2.4", "", "2017-07-14 02:40:00"},
+				{"1", "1", "4.5", "", "2017-07-14 02:40:00"},
+			},
+			[]string{"FROM beatmaps  ORDER BY id DESC LIMIT 500"},
+			[]driver.Value{},
+		},
+		{
+			"taiko with converts",
+			"/api/get_beatmaps?m=1&a=1&u=peppy&limit=10",
+			[]beatmap{
+				{"75", "1", "2.9", "", "2017-07-14 02:40:00"},
+				{"1", "1", "4.5", "", "2017-07-14 02:40:00"},
+			},
+			[]string{"WHERE beatmaps.creator = ? AND beatmaps.difficulty_taiko != 0 ORDER BY id DESC LIMIT 10"},
+			[]driver.Value{"peppy"},
+		},
+		{
+			"taiko",
+			"/api/get_beatmaps?m=1",
+			nil,
+			[]string{"WHERE beatmaps.difficulty_taiko != 0 AND beatmaps.difficulty_std = 0"},
+			[]driver.Value{},
+		},
+		{
+			"since",
+			"/api/get_beatmaps?since=2010-01-01&b=75",
+			nil,
+			[]string{
+				"IF(beatmaps.approved_date != 0, beatmaps.approved_date, beatmaps.latest_update) > ? AND beatmaps.beatmap_id = ?",
+				"ORDER BY IF(beatmaps.approved_date != 0, beatmaps.approved_date, beatmaps.latest_update) ASC LIMIT 1",
+			},
+			[]driver.Value{i(1262304000), "75"},
+		},
+	}
+	for _, tt := range tests {
+		*queries = nil
+		got := []beatmap{}
+		testRequest(t, db, GetBeatmap, tt.uri, &got)
+		if tt.want != nil && !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%q. GetBeatmap() = %v, want %v", tt.name, got, tt.want)
+		}
+		q := (*queries)[0]
+		for _, w := range tt.query {
+			if !strings.Contains(q.Query, w) {
+				t.Errorf("%q. query %q does not contain %q", tt.name, q.Query, w)
+			}
+		}
+		if !reflect.DeepEqual(q.Args, tt.args) {
+			t.Errorf("%q. args = %v, want %v", tt.name, q.Args, tt.args)
+		}
+	}
+}
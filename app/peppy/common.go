@@ -12,6 +12,8 @@ import (
 
 var modes = []string{"std", "taiko", "ctb", "mania"}
 
+var modeNames = []string{"osu!", "Taiko", "Catch the Beat", "osu!mania"}
+
 var defaultResponse = []struct{}{}
 
 func genmode(m string) string {
@@ -25,6 +27,27 @@ func genmodei(m string) int {
 	}
 	return v
 }
+
+// gensmode returns the special mode requested. Like on the v1 API, it can be
+// passed in smode, or rx=1 and ap=1 can be used as shorthands. ok is false if
+// smode is not the ID of a special mode, in which case the request must be
+// rejected with badSpecialMode rather than answered with vanilla data.
+func gensmode(c *fasthttp.RequestCtx) (s common.SpecialMode, ok bool) {
+	if !c.QueryArgs().Has("smode") {
+		return common.SpecialModeFromQuery(c.QueryArgs()), true
+	}
+	id, err := strconv.Atoi(query(c, "smode"))
+	if err != nil {
+		return common.Vanilla, false
+	}
+	return common.GetSpecialMode(id)
+}
+
+// badSpecialMode tells the client that the smode it passed does not exist.
+func badSpecialMode(c *fasthttp.RequestCtx) {
+	apiError(c, 400, "Unknown special mode.")
+}
+
 func rankable(m string) bool {
 	x := genmodei(m)
 	return x != 2
@@ -61,6 +84,19 @@ func genUser(c *fasthttp.RequestCtx, db *sqlx.DB) (string, string) {
 	return whereClause, p
 }
 
+// serverError reports err, and tells the client that something went wrong.
+func serverError(c *fasthttp.RequestCtx, err error) {
+	common.Err(c, err)
+	apiError(c, 500, "An error occurred while handling the request.")
+}
+
+// apiError writes an error in the same format used by the osu! API.
+func apiError(c *fasthttp.RequestCtx, code int, message string) {
+	json(c, code, struct {
+		Error string `json:"error"`
+	}{message})
+}
+
 func query(c *fasthttp.RequestCtx, s string) string {
 	return string(c.QueryArgs().Peek(s))
 }
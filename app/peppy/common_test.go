@@ -0,0 +1,32 @@
+package peppy
+
+import (
+	"testing"
+
+	"github.com/osu-datenshi/api/common"
+	"github.com/valyala/fasthttp"
+)
+
+func TestGensmode(t *testing.T) {
+	tests := []struct {
+		query  string
+		want   common.SpecialMode
+		wantOK bool
+	}{
+		{"", common.Vanilla, true},
+		{"rx=1", common.Relax, true},
+		{"ap=1", common.Autopilot, true},
+		{"smode=2", common.Autopilot, true},
+		{"smode=3", common.Vanilla, false},
+		{"smode=-1", common.Vanilla, false},
+		{"smode=abc", common.Vanilla, false},
+	}
+	for _, tt := range tests {
+		var c fasthttp.RequestCtx
+		c.Request.SetRequestURI("/api/get_user?" + tt.query)
+		got, ok := gensmode(&c)
+		if got != tt.want || ok != tt.wantOK {
+			t.Errorf("%q: gensmode() = %v, %v, want %v, %v", tt.query, got.Name, ok, tt.want.Name, tt.wantOK)
+		}
+	}
+}
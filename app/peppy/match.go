@@ -2,11 +2,127 @@
 package peppy
 
 import (
+	"database/sql"
+
 	"github.com/jmoiron/sqlx"
+	"github.com/osu-datenshi/api/common"
 	"github.com/valyala/fasthttp"
+	"gopkg.in/thehowl/go-osuapi.v1"
 )
 
-// GetMatch retrieves general match information.
+type match struct {
+	Info  interface{} `json:"match"`
+	Games []matchGame `json:"games"`
+}
+
+type matchGame struct {
+	osuapi.MatchGame
+	Scores []matchGameScore `json:"scores"`
+}
+
+// matchGameScore adds to osuapi.MatchGameScore the fields that go-osuapi
+// leaves out. We don't get the combo and hit counts of the players from
+// pep.py, so those are always 0.
+type matchGameScore struct {
+	osuapi.MatchGameScore
+	Rank        string `json:"rank"`
+	Perfect     string `json:"perfect"`
+	EnabledMods int    `json:"enabled_mods,string"`
+}
+
+// GetMatch retrieves general match information. The ID of the match is the
+// one given by the API, sent as match_id with the completed games on the
+// websocket, and not the one of pep.py.
 func GetMatch(c *fasthttp.RequestCtx, db *sqlx.DB) {
-	json(c, 200, defaultResponse)
+	// Like on the osu! API, a match that does not exist has 0 as its info.
+	notFound := match{Info: 0, Games: []matchGame{}}
+
+	var (
+		info      osuapi.MatchInfo
+		startTime common.UnixTimestamp
+		endTime   common.UnixTimestamp
+	)
+	err := db.QueryRow("SELECT id, name, start_time, end_time FROM matches WHERE id = ? LIMIT 1",
+		common.Int(query(c, "mp"))).Scan(&info.MatchID, &info.Name, &startTime, &endTime)
+	switch {
+	case err == sql.ErrNoRows:
+		json(c, 200, notFound)
+		return
+	case err != nil:
+		serverError(c, err)
+		return
+	}
+	info.StartTime = osuapi.MySQLDate(startTime)
+	end := osuapi.MySQLDate(endTime)
+	info.EndTime = &end
+
+	games, err := matchGames(db, info.MatchID)
+	if err != nil {
+		serverError(c, err)
+		return
+	}
+	json(c, 200, match{Info: info, Games: games})
+}
+
+func matchGames(db *sqlx.DB, matchID int) ([]matchGame, error) {
+	rows, err := db.Query(`
+SELECT
+	g.id, g.beatmap_id, g.play_mode, g.mods, g.start_time, g.end_time,
+	s.slot, s.team, s.user_id, s.score, s.mods, s.pass
+FROM match_games as g
+LEFT JOIN match_game_scores as s ON s.game_id = g.id
+WHERE g.match_id = ?
+ORDER BY g.id ASC, s.slot ASC`, matchID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	games := []matchGame{}
+	for rows.Next() {
+		var (
+			g        matchGame
+			mode     int
+			mods     int
+			start    common.UnixTimestamp
+			end      common.UnixTimestamp
+			slot     sql.NullInt64
+			team     sql.NullInt64
+			userID   sql.NullInt64
+			score    sql.NullInt64
+			userMods sql.NullInt64
+			pass     sql.NullBool
+		)
+		err := rows.Scan(
+			&g.GameID, &g.BeatmapID, &mode, &mods, &start, &end,
+			&slot, &team, &userID, &score, &userMods, &pass,
+		)
+		if err != nil {
+			return nil, err
+		}
+		if len(games) == 0 || games[len(games)-1].GameID != g.GameID {
+			g.PlayMode = osuapi.Mode(mode)
+			g.Mods = osuapi.Mods(mods)
+			g.StartTime = osuapi.MySQLDate(start)
+			endTime := osuapi.MySQLDate(end)
+			g.EndTime = &endTime
+			g.Scores = []matchGameScore{}
+			games = append(games, g)
+		}
+		if !userID.Valid {
+			continue
+		}
+		var s matchGameScore
+		s.Slot = int(slot.Int64)
+		s.Team = int(team.Int64)
+		s.UserID = int(userID.Int64)
+		s.Score = score.Int64
+		s.Pass = osuapi.OsuBool(pass.Bool)
+		s.Rank = "0"
+		s.Perfect = "0"
+		s.EnabledMods = int(userMods.Int64)
+		last := &games[len(games)-1]
+		last.Scores = append(last.Scores, s)
+	}
+	return games, rows.Err()
 }
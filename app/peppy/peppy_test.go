@@ -0,0 +1,58 @@
+package peppy
+
+import (
+	_json "encoding/json"
+	"os"
+	"testing"
+
+	"github.com/go-sql-driver/mysql"
+	"github.com/jmoiron/sqlx"
+	"github.com/valyala/fasthttp"
+)
+
+// testDatabase is the database created for the tests, dropped and created
+// again each time testDB is called.
+const testDatabase = "rippleapi_test"
+
+// testDB connects to the MySQL-compatible server in the API_TEST_DSN
+// environment variable, creates a fresh database on it and runs schema. If
+// API_TEST_DSN is not set, the test is skipped.
+func testDB(t *testing.T, schema ...string) *sqlx.DB {
+	dsn := os.Getenv("API_TEST_DSN")
+	if dsn == "" {
+		t.Skip("API_TEST_DSN is not set")
+	}
+	cfg, err := mysql.ParseDSN(dsn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	server, err := sqlx.Open("mysql", dsn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer server.Close()
+	server.MustExec("DROP DATABASE IF EXISTS " + testDatabase)
+	server.MustExec("CREATE DATABASE " + testDatabase)
+
+	cfg.DBName = testDatabase
+	db, err := sqlx.Open("mysql", cfg.FormatDSN())
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, q := range schema {
+		db.MustExec(q)
+	}
+	return db
+}
+
+// testRequest calls handler with the given request URI, and decodes its JSON
+// response into v.
+func testRequest(t *testing.T, db *sqlx.DB, handler func(*fasthttp.RequestCtx, *sqlx.DB), uri string, v interface{}) {
+	var c fasthttp.RequestCtx
+	c.Request.SetRequestURI(uri)
+	handler(&c, db)
+	err := _json.Unmarshal(c.Response.Body(), v)
+	if err != nil {
+		t.Fatalf("%s: %v (body: %s)", uri, err, c.Response.Body())
+	}
+}
@@ -0,0 +1,98 @@
+package peppy
+
+import (
+	"database/sql"
+	"encoding/base64"
+	"io/ioutil"
+	"os"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/osu-datenshi/api/common"
+	"github.com/valyala/fasthttp"
+)
+
+type replayResponse struct {
+	Content  string `json:"content"`
+	Encoding string `json:"encoding"`
+}
+
+var replayNotAvailable = struct {
+	Error string `json:"error"`
+}{"Replay not available."}
+
+// GetReplay retrieves the replay data of a score. The score can either be
+// specified by its ID through s, or like on the osu! API through the beatmap,
+// the user, the mode and optionally the mods, in which case the best score of
+// the user is used.
+func GetReplay(c *fasthttp.RequestCtx, db *sqlx.DB) {
+	token, _ := c.UserValue("token").(common.Token)
+
+	var (
+		where  string
+		params []interface{}
+	)
+	switch {
+	case query(c, "s") != "":
+		where = "s.id = ?"
+		params = append(params, query(c, "s"))
+	case query(c, "b") != "" && query(c, "u") != "":
+		smode, ok := gensmode(c)
+		if !ok {
+			badSpecialMode(c)
+			return
+		}
+		w, p := genUser(c, db)
+		where = w + ` AND b.beatmap_id = ? AND s.play_mode = ? AND s.special_mode = ?
+			AND s.completed = '3'`
+		params = append(params, p, query(c, "b"), genmodei(query(c, "m")), smode.ID)
+		if query(c, "mods") != "" {
+			where += " AND s.mods = ?"
+			params = append(params, common.Int(query(c, "mods")))
+		}
+	default:
+		json(c, 200, replayNotAvailable)
+		return
+	}
+
+	var id, userID, mode, smode int
+	err := db.QueryRow(`SELECT s.id, s.userid, s.play_mode, s.special_mode
+		FROM scores_master as s
+		INNER JOIN beatmaps as b ON b.beatmap_md5 = s.beatmap_md5
+		INNER JOIN users ON users.id = s.userid
+		WHERE `+where+` AND `+token.OnlyUserPublic(true)+`
+		LIMIT 1`, params...).Scan(&id, &userID, &mode, &smode)
+	switch {
+	case err == sql.ErrNoRows:
+		json(c, 200, replayNotAvailable)
+		return
+	case err != nil:
+		common.Err(c, err)
+		json(c, 200, replayNotAvailable)
+		return
+	}
+
+	data, err := ioutil.ReadFile(common.ReplayPath(id))
+	if err != nil {
+		if !os.IsNotExist(err) {
+			common.Err(c, err)
+		}
+		json(c, 200, replayNotAvailable)
+		return
+	}
+
+	if token.UserID != userID {
+		var first bool
+		first, err = common.NewReplayView(R, id, common.MethodData{Ctx: c}.ClientIP())
+		if err == nil && first {
+			err = common.ReplayWatched(db, userID, mode, smode)
+		}
+		if err != nil {
+			common.Err(c, err)
+		}
+	}
+
+	json(c, 200, replayResponse{
+		Content:  base64.StdEncoding.EncodeToString(data),
+		Encoding: "base64",
+	})
+}
@@ -19,6 +19,11 @@ func GetScores(c *fasthttp.RequestCtx, db *sqlx.DB) {
 		json(c, 200, defaultResponse)
 		return
 	}
+	smode, ok := gensmode(c)
+	if !ok {
+		badSpecialMode(c)
+		return
+	}
 	var beatmapMD5 string
 	err := db.Get(&beatmapMD5, "SELECT beatmap_md5 FROM beatmaps WHERE beatmap_id = ? LIMIT 1", query(c, "b"))
 	switch {
@@ -46,20 +51,21 @@ func GetScores(c *fasthttp.RequestCtx, db *sqlx.DB) {
 	mods := common.Int(query(c, "mods"))
 	rows, err := db.Query(`
 SELECT
-	s.id, s.score, u.username, s.300_count, s.100_count,
+	s.id, s.score, users.username, s.300_count, s.100_count,
 	s.50_count, s.misses_count, s.gekis_count, s.katus_count,
 	s.max_combo, s.full_combo, s.mods, users.id, s.time, s.pp,
 	s.accuracy
 FROM scores_master as s
-INNER JOIN users as u ON u.id = s.userid
+INNER JOIN users ON users.id = s.userid
 WHERE s.completed = '3'
-  AND u.privileges & 1 > 0
+  AND users.privileges & 1 > 0
   AND s.beatmap_md5 = ?
   AND s.play_mode = ?
+  AND s.special_mode = ?
   AND s.mods & ? = ?
   `+extraWhere+`
 ORDER BY `+sb+` DESC LIMIT `+strconv.Itoa(common.InString(1, query(c, "limit"), 100, 50)),
-		append([]interface{}{beatmapMD5, genmodei(query(c, "m")), mods, mods}, extraParams...)...)
+		append([]interface{}{beatmapMD5, genmodei(query(c, "m")), smode.ID, mods, mods}, extraParams...)...)
 	if err != nil {
 		common.Err(c, err)
 		json(c, 200, defaultResponse)
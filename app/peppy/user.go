@@ -4,48 +4,65 @@ package peppy
 import (
 	"database/sql"
 	"fmt"
+	"html"
+	"sort"
 	"strconv"
-
 	"strings"
+	"time"
 
 	"github.com/jmoiron/sqlx"
+	v1 "github.com/osu-datenshi/api/app/v1"
+	"github.com/osu-datenshi/api/common"
+	"github.com/osu-datenshi/lib/ocl"
 	"github.com/thehowl/go-osuapi"
 	"github.com/valyala/fasthttp"
 	"gopkg.in/redis.v5"
-	"github.com/osu-datenshi/lib/ocl"
-	"github.com/osu-datenshi/api/common"
 )
 
 // R is a redis client.
 var R *redis.Client
 
+// apiUser adds to osuapi.User the fields of get_user that go-osuapi leaves out.
+type apiUser struct {
+	osuapi.User
+	CountSSH           int `json:"count_rank_ssh,string"`
+	CountSH            int `json:"count_rank_sh,string"`
+	TotalSecondsPlayed int `json:"total_seconds_played,string"`
+}
+
 // GetUser retrieves general user information.
 func GetUser(c *fasthttp.RequestCtx, db *sqlx.DB) {
 	if query(c, "u") == "" {
 		json(c, 200, defaultResponse)
 		return
 	}
-	var user osuapi.User
+	var user apiUser
 	whereClause, p := genUser(c, db)
 	whereClause = "WHERE " + whereClause
 
 	mode := genmode(query(c, "m"))
+	smode, ok := gensmode(c)
+	if !ok {
+		badSpecialMode(c)
+		return
+	}
 
 	err := db.QueryRow(fmt.Sprintf(
 		`SELECT
 			users.id, users.username,
-			users_stats.playcount_%s, users_stats.ranked_score_%s, users_stats.total_score_%s,
-			users_stats.pp_%s, users_stats.avg_accuracy_%s,
+			IFNULL(st.playcount_%[1]s, 0), IFNULL(st.ranked_score_%[1]s, 0), IFNULL(st.total_score_%[1]s, 0),
+			IFNULL(st.pp_%[1]s, 0), IFNULL(st.avg_accuracy_%[1]s, 0), IFNULL(st.playtime_%[1]s, 0),
 			users_stats.country
 		FROM users
 		LEFT JOIN users_stats ON users_stats.id = users.id
-		%s
+		LEFT JOIN %[3]s as st ON st.id = users.id
+		%[2]s
 		LIMIT 1`,
-		mode, mode, mode, mode, mode, whereClause,
+		mode, whereClause, smode.StatsTable,
 	), p).Scan(
 		&user.UserID, &user.Username,
 		&user.Playcount, &user.RankedScore, &user.TotalScore,
-		&user.PP, &user.Accuracy,
+		&user.PP, &user.Accuracy, &user.TotalSecondsPlayed,
 		&user.Country,
 	)
 	if err != nil {
@@ -56,9 +73,104 @@ func GetUser(c *fasthttp.RequestCtx, db *sqlx.DB) {
 		return
 	}
 
-	user.Rank = int(R.ZRevRank("ripple:leaderboard:"+mode, strconv.Itoa(user.UserID)).Val()) + 1
-	user.CountryRank = int(R.ZRevRank("ripple:leaderboard:"+mode+":"+strings.ToLower(user.Country), strconv.Itoa(user.UserID)).Val()) + 1
+	user.Rank = int(R.ZRevRank(smode.LeaderboardKey()+mode, strconv.Itoa(user.UserID)).Val()) + 1
+	user.CountryRank = int(R.ZRevRank(smode.LeaderboardKey()+mode+":"+strings.ToLower(user.Country), strconv.Itoa(user.UserID)).Val()) + 1
 	user.Level = ocl.GetLevelPrecise(user.TotalScore)
 
-	json(c, 200, []osuapi.User{user})
+	err = db.QueryRow(`SELECT
+			IFNULL(SUM(300_count), 0), IFNULL(SUM(100_count), 0), IFNULL(SUM(50_count), 0)
+		FROM scores_master
+		WHERE userid = ? AND play_mode = ? AND special_mode = ?`,
+		user.UserID, genmodei(query(c, "m")), smode.ID).Scan(&user.Count300, &user.Count100, &user.Count50)
+	if err != nil {
+		common.Err(c, err)
+	}
+
+	grades, err := v1.UserGrades(db, R, user.UserID, genmodei(query(c, "m")), smode)
+	if err != nil {
+		common.Err(c, err)
+	} else {
+		user.CountSSH = grades.XH
+		user.CountSS = grades.X
+		user.CountSH = grades.SH
+		user.CountS = grades.S
+		user.CountA = grades.A
+	}
+
+	user.Events, err = userEvents(db, user.User, genmodei(query(c, "m")), smode.ID,
+		common.InString(1, query(c, "event_days"), 31, 1))
+	if err != nil {
+		common.Err(c, err)
+	}
+
+	json(c, 200, []apiUser{user})
+}
+
+// userEvents generates the events of the last days days of an user, like the
+// osu! API would: first place ranks and unlocked achievements.
+func userEvents(db *sqlx.DB, u osuapi.User, mode, smode, days int) ([]osuapi.Event, error) {
+	since := time.Now().AddDate(0, 0, -days).Unix()
+	events := []osuapi.Event{}
+
+	rows, err := db.Query(`SELECT
+			b.beatmap_id, b.beatmapset_id, b.song_name, s.time
+		FROM scores_first as sf
+		INNER JOIN scores_master as s ON s.id = sf.scoreid
+		INNER JOIN beatmaps as b ON b.beatmap_md5 = s.beatmap_md5
+		WHERE sf.userid = ? AND s.play_mode = ? AND s.special_mode = ? AND s.time >= ?
+		ORDER BY s.time DESC`, u.UserID, mode, smode, since)
+	if err != nil {
+		return events, err
+	}
+	for rows.Next() {
+		var (
+			e        osuapi.Event
+			songName string
+			date     common.UnixTimestamp
+		)
+		err := rows.Scan(&e.BeatmapID, &e.BeatmapsetID, &songName, &date)
+		if err != nil {
+			rows.Close()
+			return events, err
+		}
+		e.DisplayHTML = fmt.Sprintf(
+			"<img src='/images/A_small.png'/> <b><a href='/u/%d'>%s</a></b> achieved rank #1 on <a href='/b/%d?m=%d'>%s</a> (%s)",
+			u.UserID, html.EscapeString(u.Username), e.BeatmapID, mode, html.EscapeString(songName), modeNames[mode],
+		)
+		e.Date = osuapi.MySQLDate(date)
+		e.Epicfactor = 1
+		events = append(events, e)
+	}
+	rows.Close()
+
+	rows, err = db.Query(`SELECT
+			a.name, ua.time
+		FROM users_achievements as ua
+		INNER JOIN achievements as a ON a.id = ua.achievement_id
+		WHERE ua.user_id = ? AND ua.time >= ?
+		ORDER BY ua.time DESC`, u.UserID, since)
+	if err != nil {
+		return events, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var (
+			e    osuapi.Event
+			name string
+			date common.UnixTimestamp
+		)
+		err := rows.Scan(&name, &date)
+		if err != nil {
+			return events, err
+		}
+		e.DisplayHTML = fmt.Sprintf("<b><a href='/u/%d'>%s</a></b> unlocked the \"<b>%s</b>\" medal!",
+			u.UserID, html.EscapeString(u.Username), html.EscapeString(name))
+		e.Date = osuapi.MySQLDate(date)
+		e.Epicfactor = 1
+		events = append(events, e)
+	}
+	sort.Slice(events, func(i, j int) bool {
+		return events[i].Date.GetTime().After(events[j].Date.GetTime())
+	})
+	return events, rows.Err()
 }
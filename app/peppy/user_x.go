@@ -5,12 +5,20 @@ import (
 	"strings"
 
 	"github.com/jmoiron/sqlx"
-	"github.com/valyala/fasthttp"
-	"gopkg.in/thehowl/go-osuapi.v1"
 	"github.com/osu-datenshi/api/common"
 	"github.com/osu-datenshi/lib/getrank"
+	"github.com/valyala/fasthttp"
+	"gopkg.in/thehowl/go-osuapi.v1"
 )
 
+// userScore adds to osuapi.GUSScore the fields of get_user_best and
+// get_user_recent that go-osuapi leaves out.
+type userScore struct {
+	ScoreID int64 `json:"score_id,string"`
+	osuapi.GUSScore
+	ReplayAvailable osuapi.OsuBool `json:"replay_available"`
+}
+
 // GetUserRecent retrieves an user's recent scores.
 func GetUserRecent(c *fasthttp.RequestCtx, db *sqlx.DB) {
 	getUserX(c, db, "ORDER BY s.time DESC", common.InString(1, query(c, "limit"), 50, 10))
@@ -24,48 +32,57 @@ func GetUserBest(c *fasthttp.RequestCtx, db *sqlx.DB) {
 	} else {
 		sb = "s.score"
 	}
-	getUserX(c, db, "AND completed = '3' ORDER BY "+sb+" DESC", common.InString(1, query(c, "limit"), 100, 10))
+	getUserX(c, db, "AND s.completed = '3' ORDER BY "+sb+" DESC", common.InString(1, query(c, "limit"), 100, 10))
 }
 
 func getUserX(c *fasthttp.RequestCtx, db *sqlx.DB, orderBy string, limit int) {
+	smode, ok := gensmode(c)
+	if !ok {
+		badSpecialMode(c)
+		return
+	}
 	whereClause, p := genUser(c, db)
+	// The columns starting with a digit are quoted, as not every
+	// MySQL-compatible server can parse them otherwise.
 	sqlQuery := fmt.Sprintf(
 		`SELECT
-			b.beatmap_id, s.score, s.max_combo,
-			s.300_count, s.100_count, s.50_count,
+			s.id, b.beatmap_id, s.score, s.max_combo,
+			s.`+"`300_count`, s.`100_count`, s.`50_count`"+`,
 			s.gekis_count, s.katus_count, s.misses_count,
 			s.full_combo, s.mods, users.id, s.time,
-			s.pp, s.accuracy
+			s.pp, s.accuracy, s.completed
 		FROM scores_master as s
-		LEFT JOIN b ON using(beatmap_md5)
-		LEFT JOIN u as u ON s.userid = u.id
-		WHERE %s AND s.play_mode = ? AND u.privileges & 1 > 0
+		LEFT JOIN beatmaps as b ON b.beatmap_md5 = s.beatmap_md5
+		INNER JOIN users ON users.id = s.userid
+		WHERE %s AND s.play_mode = ? AND s.special_mode = ? AND users.privileges & 1 > 0
 		%s
 		LIMIT %d`, whereClause, orderBy, limit,
 	)
-	scores := make([]osuapi.GUSScore, 0, limit)
+	scores := make([]userScore, 0, limit)
 	m := genmodei(query(c, "m"))
-	rows, err := db.Query(sqlQuery, p, m)
+	rows, err := db.Query(sqlQuery, p, m, smode.ID)
 	if err != nil {
 		json(c, 200, defaultResponse)
 		common.Err(c, err)
 		return
 	}
+	defer rows.Close()
 	for rows.Next() {
 		var (
-			curscore osuapi.GUSScore
-			rawTime  common.UnixTimestamp
-			acc      float64
-			fc       bool
-			mods     int
-			bid      *int
+			curscore  userScore
+			rawTime   common.UnixTimestamp
+			acc       float64
+			fc        bool
+			mods      int
+			bid       *int
+			completed int
 		)
 		err := rows.Scan(
-			&bid, &curscore.Score.Score, &curscore.MaxCombo,
+			&curscore.ScoreID, &bid, &curscore.Score.Score, &curscore.MaxCombo,
 			&curscore.Count300, &curscore.Count100, &curscore.Count50,
 			&curscore.CountGeki, &curscore.CountKatu, &curscore.CountMiss,
 			&fc, &mods, &curscore.UserID, &rawTime,
-			&curscore.PP, &acc,
+			&curscore.PP, &acc, &completed,
 		)
 		if err != nil {
 			json(c, 200, defaultResponse)
@@ -89,6 +106,8 @@ func getUserX(c *fasthttp.RequestCtx, db *sqlx.DB, orderBy string, limit int) {
 			curscore.Count50,
 			curscore.CountMiss,
 		))
+		// Replays are saved for all passed scores.
+		curscore.ReplayAvailable = osuapi.OsuBool(completed >= 2)
 		scores = append(scores, curscore)
 	}
 	json(c, 200, scores)
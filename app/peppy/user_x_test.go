@@ -0,0 +1,244 @@
+package peppy
+
+import (
+	"database/sql/driver"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/osu-datenshi/api/internal/fakesql"
+	"github.com/valyala/fasthttp"
+)
+
+var userXSchema = []string{
+	`CREATE TABLE users (
+		id INT NOT NULL PRIMARY KEY,
+		username VARCHAR(32) NOT NULL,
+		username_safe VARCHAR(32) NOT NULL,
+		privileges BIGINT NOT NULL
+	)`,
+	`CREATE TABLE beatmaps (
+		id INT NOT NULL PRIMARY KEY,
+		beatmap_id INT NOT NULL,
+		beatmapset_id INT NOT NULL,
+		beatmap_md5 VARCHAR(32) NOT NULL
+	)`,
+	`CREATE TABLE scores_master (
+		id INT NOT NULL PRIMARY KEY,
+		beatmap_md5 VARCHAR(32) NOT NULL,
+		userid INT NOT NULL,
+		score BIGINT NOT NULL,
+		max_combo INT NOT NULL,
+		full_combo TINYINT(1) NOT NULL,
+		mods INT NOT NULL,
+		300_count INT NOT NULL,
+		100_count INT NOT NULL,
+		50_count INT NOT NULL,
+		gekis_count INT NOT NULL,
+		katus_count INT NOT NULL,
+		misses_count INT NOT NULL,
+		time INT NOT NULL,
+		play_mode TINYINT NOT NULL,
+		special_mode TINYINT NOT NULL,
+		completed TINYINT NOT NULL,
+		accuracy DOUBLE NOT NULL,
+		pp DOUBLE NOT NULL
+	)`,
+	`INSERT INTO users VALUES
+		(1000, 'Howl', 'howl', 3),
+		(1001, 'Restricted', 'restricted', 2)`,
+	`INSERT INTO beatmaps VALUES
+		(1, 75, 1, 'a5b99395a42bd55bc5eb1d2411cbdf8b'),
+		(2, 129891, 39804, 'da8aae79c8f3306b5d65ec951874a7fb')`,
+	`INSERT INTO scores_master VALUES
+		(1, 'a5b99395a42bd55bc5eb1d2411cbdf8b', 1000, 1000000, 314, 1, 8, 200, 0, 0, 0, 0, 0, 1500000000, 0, 0, 3, 100, 150),
+		(2, 'da8aae79c8f3306b5d65ec951874a7fb', 1000, 500000, 100, 0, 0, 90, 10, 0, 0, 0, 1, 1500000100, 0, 0, 3, 93.3, 300),
+		(3, 'da8aae79c8f3306b5d65ec951874a7fb', 1000, 100000, 20, 0, 0, 20, 10, 5, 0, 0, 10, 1500000200, 0, 0, 1, 50, 0),
+		(4, 'a5b99395a42bd55bc5eb1d2411cbdf8b', 1000, 900000, 314, 1, 128, 200, 0, 0, 0, 0, 0, 1500000300, 0, 1, 3, 100, 400),
+		(5, 'a5b99395a42bd55bc5eb1d2411cbdf8b', 1001, 1000000, 314, 1, 0, 200, 0, 0, 0, 0, 0, 1500000400, 0, 0, 3, 100, 200),
+		(6, 'deadbeefdeadbeefdeadbeefdeadbeef', 1000, 700000, 50, 0, 0, 50, 0, 0, 0, 0, 0, 1500000500, 0, 0, 2, 100, 0)`,
+}
+
+func TestGetUserX(t *testing.T) {
+	db := testDB(t, userXSchema...)
+	defer db.Close()
+
+	type score struct {
+		ScoreID         string `json:"score_id"`
+		BeatmapID       string `json:"beatmap_id"`
+		Rank            string `json:"rank"`
+		Perfect         string `json:"perfect"`
+		EnabledMods     string `json:"enabled_mods"`
+		ReplayAvailable string `json:"replay_available"`
+	}
+	tests := []struct {
+		name string
+		best bool
+		uri  string
+		want []score
+	}{
+		{
+			"best",
+			true,
+			"/api/get_user_best?u=1000",
+			[]score{
+				{"2", "129891", "B", "0", "0", "1"},
+				{"1", "75", "SSH", "1", "8", "1"},
+			},
+		},
+		{
+			"best relax",
+			true,
+			"/api/get_user_best?u=howl&type=string&smode=1",
+			[]score{
+				{"4", "75", "SS", "1", "128", "1"},
+			},
+		},
+		{
+			"best rx shorthand",
+			true,
+			"/api/get_user_best?u=1000&rx=1",
+			[]score{
+				{"4", "75", "SS", "1", "128", "1"},
+			},
+		},
+		{
+			"recent",
+			false,
+			"/api/get_user_recent?u=1000",
+			[]score{
+				{"6", "0", "SS", "0", "0", "1"},
+				{"3", "129891", "D", "0", "0", "0"},
+				{"2", "129891", "B", "0", "0", "1"},
+				{"1", "75", "SSH", "1", "8", "1"},
+			},
+		},
+		{
+			"recent limit",
+			false,
+			"/api/get_user_recent?u=1000&limit=1",
+			[]score{
+				{"6", "0", "SS", "0", "0", "1"},
+			},
+		},
+		{
+			"restricted",
+			true,
+			"/api/get_user_best?u=1001",
+			[]score{},
+		},
+	}
+	for _, tt := range tests {
+		handler := GetUserRecent
+		if tt.best {
+			handler = GetUserBest
+		}
+		got := []score{}
+		testRequest(t, db, handler, tt.uri, &got)
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%q. getUserX() = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestGetUserXQuery(t *testing.T) {
+	i := func(x int64) driver.Value { return x }
+	db, queries := fakesql.Open(
+		fakesql.Result{
+			Match:   "SELECT id FROM users",
+			Columns: []string{"id"},
+			Rows:    [][]driver.Value{{i(1000)}},
+		},
+		fakesql.Result{
+			Match: "FROM scores_master",
+			Columns: []string{
+				"id", "beatmap_id", "score", "max_combo", "300_count", "100_count", "50_count",
+				"gekis_count", "katus_count", "misses_count", "full_combo", "mods", "id", "time",
+				"pp", "accuracy", "completed",
+			},
+			Rows: [][]driver.Value{
+				{i(1), i(75), i(1000000), i(314), i(200), i(0), i(0),
+					i(0), i(0), i(0), i(1), i(8), i(1000), i(1500000000),
+					150.0, 100.0, i(3)},
+				// A score on a beatmap that is not in the database, which
+				// was not passed.
+				{i(3), nil, i(100000), i(20), i(20), i(10), i(5),
+					i(0), i(0), i(10), i(0), i(0), i(1000), i(1500000200),
+					0.0, 50.0, i(1)},
+			},
+		},
+	)
+	defer db.Close()
+
+	type score struct {
+		ScoreID         string `json:"score_id"`
+		BeatmapID       string `json:"beatmap_id"`
+		Rank            string `json:"rank"`
+		Perfect         string `json:"perfect"`
+		EnabledMods     string `json:"enabled_mods"`
+		ReplayAvailable string `json:"replay_available"`
+	}
+	got := []score{}
+	testRequest(t, db, GetUserRecent, "/api/get_user_recent?u=1000&smode=1", &got)
+	want := []score{
+		{"1", "75", "SSH", "1", "8", "1"},
+		{"3", "0", "D", "0", "0", "0"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("getUserX() = %v, want %v", got, want)
+	}
+
+	tests := []struct {
+		name    string
+		handler func(*fasthttp.RequestCtx, *sqlx.DB)
+		uri     string
+		want    []string
+		args    []driver.Value
+	}{
+		{
+			"recent",
+			GetUserRecent,
+			"/api/get_user_recent?u=1000&smode=1",
+			[]string{
+				"LEFT JOIN beatmaps as b ON b.beatmap_md5 = s.beatmap_md5",
+				"INNER JOIN users ON users.id = s.userid",
+				"users.id = ? AND s.play_mode = ? AND s.special_mode = ? AND users.privileges & 1 > 0",
+				"ORDER BY s.time DESC",
+				"LIMIT 10",
+			},
+			[]driver.Value{"1000", i(0), i(1)},
+		},
+		{
+			"best",
+			GetUserBest,
+			"/api/get_user_best?u=howl&type=string&m=3&limit=5",
+			[]string{
+				"users.username_safe = ? AND s.play_mode = ?",
+				"AND s.completed = '3' ORDER BY s.pp DESC",
+				"LIMIT 5",
+			},
+			[]driver.Value{"howl", i(3), i(0)},
+		},
+		{
+			"best unrankable",
+			GetUserBest,
+			"/api/get_user_best?u=1000&m=2&ap=1",
+			[]string{"AND s.completed = '3' ORDER BY s.score DESC"},
+			[]driver.Value{"1000", i(2), i(2)},
+		},
+	}
+	for _, tt := range tests {
+		*queries = nil
+		testRequest(t, db, tt.handler, tt.uri, &[]score{})
+		q := (*queries)[len(*queries)-1]
+		for _, w := range tt.want {
+			if !strings.Contains(q.Query, w) {
+				t.Errorf("%q. query %q does not contain %q", tt.name, q.Query, w)
+			}
+		}
+		if !reflect.DeepEqual(q.Args, tt.args) {
+			t.Errorf("%q. args = %v, want %v", tt.name, q.Args, tt.args)
+		}
+	}
+}
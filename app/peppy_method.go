@@ -1,6 +1,9 @@
 package app
 
 import (
+	"encoding/json"
+	"strconv"
+
 	"github.com/jmoiron/sqlx"
 	"github.com/valyala/fasthttp"
 )
@@ -8,12 +11,51 @@ import (
 // PeppyMethod generates a method for the peppyapi
 func PeppyMethod(a func(c *fasthttp.RequestCtx, db *sqlx.DB)) fasthttp.RequestHandler {
 	return func(c *fasthttp.RequestCtx) {
-		doggo.Incr("requests.peppy", nil, 1)
-
 		c.Response.Header.SetContentType("application/json; charset=utf-8")
 
+		// Like on the osu! API, the key is passed in k and it is mandatory.
+		k := string(c.QueryArgs().Peek("k"))
+		if k == "" {
+			doggo.Incr("requests.peppy", []string{"unauthorised"}, 1)
+			peppyError(c, 401, "Please provide a valid API key.")
+			return
+		}
+		token, exists := GetTokenFull(k, db)
+		if !exists {
+			doggo.Incr("requests.peppy", []string{"unauthorised"}, 1)
+			peppyError(c, 401, "Please provide a valid API key.")
+			return
+		}
+
+		doggo.Incr("requests.peppy", []string{"user:" + strconv.Itoa(token.UserID)}, 1)
+
+		if !rateLimit(c, "peppy:k:"+strconv.Itoa(token.ID), peppyRequestsPerMinute()) {
+			doggo.Incr("requests.peppy.limited", []string{"user:" + strconv.Itoa(token.UserID)}, 1)
+			peppyError(c, 429, "Too many requests, slow down.")
+			return
+		}
+
+		// so that the handlers can know who is making the request
+		c.SetUserValue("token", token)
+
 		// I have no idea how, but I manged to accidentally string the first 4
 		// letters of the alphabet into a single function call.
 		a(c, db)
 	}
 }
+
+// peppyError writes an error in the same format used by the osu! API.
+func peppyError(c *fasthttp.RequestCtx, code int, message string) {
+	c.SetStatusCode(code)
+	data, _ := json.Marshal(struct {
+		Error string `json:"error"`
+	}{message})
+	c.Write(data)
+}
+
+func peppyRequestsPerMinute() int {
+	if cf.PeppyRequestsPerMinute < 1 {
+		return 60
+	}
+	return cf.PeppyRequestsPerMinute
+}
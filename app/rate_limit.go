@@ -0,0 +1,101 @@
+package app
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/osu-datenshi/api/common"
+	"github.com/osu-datenshi/api/limit"
+	"github.com/valyala/fasthttp"
+)
+
+// rateLimit counts a request in the bucket key, which allows perMinute
+// requests per minute, and tells the client about the state of the bucket
+// through the X-RateLimit-* headers. It returns false if the request went
+// over the limit, in which case Retry-After is set as well.
+// If perMinute is negative, requests are never limited.
+func rateLimit(c *fasthttp.RequestCtx, key string, perMinute int) bool {
+	if perMinute < 0 {
+		return true
+	}
+	res := limit.Take(key, perMinute)
+
+	h := &c.Response.Header
+	h.Set("X-RateLimit-Limit", strconv.Itoa(res.Limit))
+	h.Set("X-RateLimit-Remaining", strconv.Itoa(res.Remaining))
+	h.Set("X-RateLimit-Reset", strconv.FormatInt(time.Now().Add(res.ResetAfter).Unix(), 10))
+	if !res.Allowed {
+		h.Set("Retry-After", strconv.Itoa(int((res.RetryAfter+time.Second-1)/time.Second)))
+	}
+	return res.Allowed
+}
+
+// methodAllowed applies the rate limit of a v1 or v2 method to the request.
+// Requests made by hanayo are never limited, as it makes them on behalf of
+// all of its visitors.
+func methodAllowed(md common.MethodData) bool {
+	if isHanayo(md.Ctx) {
+		return true
+	}
+	key, perMinute := methodRateLimit(md)
+	return rateLimit(md.Ctx, key, perMinute)
+}
+
+// Rate limit groups. Every group has its own buckets, so that the heavier
+// methods don't use up the budget of the others. The methods which are not
+// registered under a group are in groupRead or groupWrite, depending on
+// whether they are GET or POST.
+const (
+	groupRead   = "read"
+	groupWrite  = "write"
+	groupSearch = "search"
+	groupReplay = "replay"
+)
+
+// rateLimitGroupKey is the user value of the request holding its group.
+const rateLimitGroupKey = "rate_limit_group"
+
+// methodRateLimit returns the bucket and the limit for a request to a v1 or
+// v2 method. Requests are counted in the bucket of their group, by token if
+// the client is authenticated, otherwise by IP address.
+func methodRateLimit(md common.MethodData) (key string, perMinute int) {
+	group, _ := md.Ctx.UserValue(rateLimitGroupKey).(string)
+	if group == "" {
+		group = groupRead
+		if md.Ctx.IsPost() {
+			group = groupWrite
+		}
+	}
+	anonymous, authenticated := groupLimits(group)
+	if md.User.ID != 0 {
+		return group + ":t:" + strconv.Itoa(md.User.ID), authenticated
+	}
+	return group + ":ip:" + md.ClientIP(), anonymous
+}
+
+// groupLimits returns the requests per minute allowed in a group for each IP
+// address and for each token.
+func groupLimits(group string) (anonymous, authenticated int) {
+	switch group {
+	case groupWrite:
+		return confLimit(cf.WriteRequestsPerMinute, common.DefaultWriteRequestsPerMinute),
+			confLimit(cf.WriteTokenRequestsPerMinute, common.DefaultWriteTokenRequestsPerMinute)
+	case groupSearch:
+		return confLimit(cf.SearchRequestsPerMinute, common.DefaultSearchRequestsPerMinute),
+			confLimit(cf.SearchTokenRequestsPerMinute, common.DefaultSearchTokenRequestsPerMinute)
+	case groupReplay:
+		return confLimit(cf.ReplayRequestsPerMinute, common.DefaultReplayRequestsPerMinute),
+			confLimit(cf.ReplayTokenRequestsPerMinute, common.DefaultReplayTokenRequestsPerMinute)
+	default:
+		return confLimit(cf.ReadRequestsPerMinute, common.DefaultReadRequestsPerMinute),
+			confLimit(cf.ReadTokenRequestsPerMinute, common.DefaultReadTokenRequestsPerMinute)
+	}
+}
+
+// confLimit returns def if the limit was not set in the configuration.
+func confLimit(v, def int) int {
+	if v == 0 {
+		return def
+	}
+	return v
+}
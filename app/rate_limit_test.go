@@ -0,0 +1,39 @@
+package app
+
+import (
+	"testing"
+
+	"github.com/osu-datenshi/api/common"
+	"github.com/valyala/fasthttp"
+)
+
+func Test_methodRateLimit(t *testing.T) {
+	tests := []struct {
+		name      string
+		method    string
+		group     string
+		userID    int
+		want      string
+		wantLimit int
+	}{
+		{"read", "GET", "", 0, "read:ip:1.2.3.4", common.DefaultReadRequestsPerMinute},
+		{"read token", "GET", "", 1000, "read:t:1000", common.DefaultReadTokenRequestsPerMinute},
+		{"write", "POST", "", 0, "write:ip:1.2.3.4", common.DefaultWriteRequestsPerMinute},
+		{"search", "GET", groupSearch, 0, "search:ip:1.2.3.4", common.DefaultSearchRequestsPerMinute},
+		{"replay token", "GET", groupReplay, 1000, "replay:t:1000", common.DefaultReplayTokenRequestsPerMinute},
+	}
+	for _, tt := range tests {
+		var c fasthttp.RequestCtx
+		c.Request.Header.SetMethod(tt.method)
+		c.Request.Header.Set("X-Real-Ip", "1.2.3.4")
+		if tt.group != "" {
+			c.SetUserValue(rateLimitGroupKey, tt.group)
+		}
+		md := common.MethodData{Ctx: &c}
+		md.User.ID = tt.userID
+		key, perMinute := methodRateLimit(md)
+		if key != tt.want || perMinute != tt.wantLimit {
+			t.Errorf("%q. methodRateLimit() = %v, %v, want %v, %v", tt.name, key, perMinute, tt.want, tt.wantLimit)
+		}
+	}
+}
@@ -14,13 +14,29 @@ import (
 
 type router struct {
 	r *fasthttprouter.Router
+	// group is the rate limit group of the methods registered through the
+	// router. If it is empty, it depends on the HTTP method.
+	group string
+}
+
+// Group returns a router which registers the methods under the rate limit
+// group, so that they are counted in buckets of their own.
+func (r router) Group(group string) router {
+	r.group = group
+	return r
 }
 
 func (r router) Method(path string, f func(md common.MethodData) common.CodeMessager, privilegesNeeded ...int) {
-	r.r.GET(path, wrap(Method(f, privilegesNeeded...)))
+	r.r.GET(path, wrap(r.limited(Method(f, privilegesNeeded...))))
+}
+func (r router) CachedMethod(path string, f func(md common.MethodData) common.CodeMessager, rule CacheRule, privilegesNeeded ...int) {
+	r.r.GET(path, wrap(r.limited(CachedMethod(f, rule, privilegesNeeded...))))
 }
 func (r router) POSTMethod(path string, f func(md common.MethodData) common.CodeMessager, privilegesNeeded ...int) {
-	r.r.POST(path, wrap(Method(f, privilegesNeeded...)))
+	r.r.POST(path, wrap(r.limited(Method(f, privilegesNeeded...))))
+}
+func (r router) V2Method(path string, f func(md common.MethodData) common.CodeMessager, privilegesNeeded ...int) {
+	r.r.GET(path, wrap(r.limited(V2Method(f, privilegesNeeded...))))
 }
 func (r router) Peppy(path string, a func(c *fasthttp.RequestCtx, db *sqlx.DB)) {
 	r.r.GET(path, wrap(PeppyMethod(a)))
@@ -32,6 +48,17 @@ func (r router) PlainGET(path string, handle fasthttp.RequestHandler) {
 	r.r.GET(path, handle)
 }
 
+// limited tells the rate limiter the group of the requests to handle.
+func (r router) limited(handle fasthttp.RequestHandler) fasthttp.RequestHandler {
+	if r.group == "" {
+		return handle
+	}
+	return func(c *fasthttp.RequestCtx) {
+		c.SetUserValue(rateLimitGroupKey, r.group)
+		handle(c)
+	}
+}
+
 const (
 	// \x1b is escape code for ESC
 	// <ESC>[<n>m is escape sequence for a certain colour
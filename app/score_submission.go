@@ -0,0 +1,92 @@
+package app
+
+import (
+	"fmt"
+
+	v1 "github.com/osu-datenshi/api/app/v1"
+	"github.com/osu-datenshi/api/common"
+)
+
+// submittedScore is a score that has just been submitted to the score server.
+type submittedScore struct {
+	ID          int `db:"id"`
+	UserID      int `db:"userid"`
+	PlayMode    int `db:"play_mode"`
+	SpecialMode int `db:"special_mode"`
+}
+
+// specialMode returns the special mode of the score, and whether it is known.
+func (s submittedScore) specialMode() (common.SpecialMode, bool) {
+	return common.GetSpecialMode(s.SpecialMode)
+}
+
+// scoreSubmissionHandlers are called, each in its own goroutine, for every
+// score that is submitted.
+var scoreSubmissionHandlers = []func(s submittedScore){
+	dropUserCache,
+	updateClanStats,
+	updateUserPeaks,
+	updateUserGrades,
+}
+
+// updateClanStats updates the stats of the clan of the user who submitted
+// the score.
+func updateClanStats(s submittedScore) {
+	sm, ok := s.specialMode()
+	if !ok {
+		return
+	}
+	err := v1.UpdateUserClanStats(db, red, s.UserID, s.PlayMode, sm)
+	if err != nil {
+		common.GenericError(err)
+	}
+}
+
+// updateUserPeaks updates the peak pp and ranks of the user who submitted the
+// score.
+func updateUserPeaks(s submittedScore) {
+	sm, ok := s.specialMode()
+	if !ok {
+		return
+	}
+	err := v1.UpdateUserPeaks(db, red, s.UserID, s.PlayMode, sm)
+	if err != nil {
+		common.GenericError(err)
+	}
+}
+
+// updateUserGrades updates the grade counts of the user who submitted the
+// score.
+func updateUserGrades(s submittedScore) {
+	err := v1.UpdateUserGrades(db, red, s.ID)
+	if err != nil {
+		common.GenericError(err)
+	}
+}
+
+// scoreSubmissionListener listens on api:score_submission, where the score
+// server publishes the ID of every score that is submitted, and passes the
+// scores to the scoreSubmissionHandlers.
+func scoreSubmissionListener() {
+	ps, err := red.Subscribe("api:score_submission")
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+	for {
+		msg, err := ps.ReceiveMessage()
+		if err != nil {
+			fmt.Println(err.Error())
+			return
+		}
+		var s submittedScore
+		err = db.Get(&s, "SELECT id, userid, play_mode, special_mode FROM scores_master WHERE id = ? LIMIT 1", msg.Payload)
+		if err != nil {
+			common.GenericError(err)
+			continue
+		}
+		for _, h := range scoreSubmissionHandlers {
+			go h(s)
+		}
+	}
+}
@@ -0,0 +1,80 @@
+package app
+
+import (
+	"database/sql/driver"
+	"strings"
+	"testing"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/osu-datenshi/api/common"
+	"github.com/osu-datenshi/api/internal/fakesql"
+	"gopkg.in/redis.v5"
+)
+
+func Test_updateClanStats(t *testing.T) {
+	i := func(x int64) driver.Value { return x }
+	tests := []struct {
+		name     string
+		smode    int
+		key      string
+		table    string
+		noUpdate bool
+	}{
+		{"vanilla", 0, "ripple:clan_leaderboard:taiko", "users_stats", false},
+		{"relax", 1, "ripple:clan_leaderboard_relax:taiko", "rx_stats", false},
+		{"autopilot", 2, "ripple:clan_leaderboard_autopilot:taiko", "ap_stats", false},
+		{"unknown", 3, "", "", true},
+	}
+	oldDB, oldRed := db, red
+	defer func() { db, red = oldDB, oldRed }()
+	for _, tt := range tests {
+		s, err := miniredis.Run()
+		if err != nil {
+			t.Fatal(err)
+		}
+		red = redis.NewClient(&redis.Options{Addr: s.Addr()})
+		var queries *[]fakesql.Query
+		db, queries = fakesql.Open(
+			fakesql.Result{
+				Match:   "SELECT clan FROM user_clans",
+				Columns: []string{"clan"},
+				Rows:    [][]driver.Value{{i(7)}},
+			},
+			fakesql.Result{
+				Match:   "COUNT(*)",
+				Columns: []string{"members", "pp", "ranked_score", "total_score", "playcount", "replays_watched", "total_hits", "accuracy"},
+				Rows:    [][]driver.Value{{i(2), i(300), i(1000), i(2000), i(30), i(4), i(500), 98.5}},
+			},
+		)
+
+		updateClanStats(submittedScore{ID: 1, UserID: 1000, PlayMode: 1, SpecialMode: tt.smode})
+
+		if tt.noUpdate {
+			if len(*queries) != 0 || len(s.Keys()) != 0 {
+				t.Errorf("%q. ran %v and set %v, want nothing", tt.name, *queries, s.Keys())
+			}
+			s.Close()
+			continue
+		}
+		if len(*queries) != 2 || !strings.Contains((*queries)[1].Query, "INNER JOIN "+tt.table+" st") {
+			t.Errorf("%q. queries = %v, want the totals from %v", tt.name, *queries, tt.table)
+		}
+		for _, sm := range common.SpecialModes {
+			key := "ripple:clan_leaderboard" + sm.KeySuffix + ":taiko"
+			if key == tt.key {
+				continue
+			}
+			if s.Exists(key) {
+				t.Errorf("%q. %v was updated, want only %v", tt.name, key, tt.key)
+			}
+		}
+		if score, err := s.ZScore(tt.key, "7"); err != nil || score != 100 {
+			t.Errorf("%q. score of the clan in %v = %v, %v, want 100", tt.name, tt.key, score, err)
+		}
+		statsKey := strings.Replace(tt.key, "clan_leaderboard", "clan_stats", 1) + ":7"
+		if acc := s.HGet(statsKey, "accuracy"); acc != "98.5" {
+			t.Errorf("%q. accuracy in %v = %q, want 98.5", tt.name, statsKey, acc)
+		}
+		s.Close()
+	}
+}
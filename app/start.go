@@ -12,8 +12,10 @@ import (
 	"github.com/osu-datenshi/api/app/internals"
 	"github.com/osu-datenshi/api/app/peppy"
 	v1 "github.com/osu-datenshi/api/app/v1"
+	v2 "github.com/osu-datenshi/api/app/v2"
 	"github.com/osu-datenshi/api/app/websockets"
 	"github.com/osu-datenshi/api/common"
+	"github.com/osu-datenshi/api/limit"
 
 	//Add-on
 	"github.com/osu-datenshi/hmrapi"
@@ -33,7 +35,9 @@ func Start(conf common.Conf, dbO *sqlx.DB) *fhr.Router {
 	cf = conf
 
 	rawRouter := fhr.New()
-	r := router{rawRouter}
+	r := router{r: rawRouter}
+	search := r.Group(groupSearch)
+	replay := r.Group(groupReplay)
 	// TODO: add back gzip
 	// TODO: add logging
 	// TODO: add sentry panic recovering
@@ -66,6 +70,11 @@ func Start(conf common.Conf, dbO *sqlx.DB) *fhr.Router {
 	})
 	peppy.R = red
 
+	// rate limits are shared among all the API instances through redis
+	limit.DefaultLimiter = limit.NewRedisLimiter(red)
+
+	go scoreSubmissionListener()
+
 	// token updater
 	go tokenUpdater(db)
 
@@ -75,6 +84,12 @@ func Start(conf common.Conf, dbO *sqlx.DB) *fhr.Router {
 	// start load achievements
 	go v1.LoadAchievementsEvery(db, time.Minute*10)
 
+	// keep the clan leaderboard in line with restrictions and clan changes
+	go v1.LoadClanLeaderboardEvery(db, red, time.Hour)
+
+	// record the daily pp and rank history of the users
+	go v1.SnapshotUserHistoryDaily(db, red)
+
 	// peppyapi
 	{
 		r.Peppy("/api/get_user", peppy.GetUser)
@@ -83,6 +98,7 @@ func Start(conf common.Conf, dbO *sqlx.DB) *fhr.Router {
 		r.Peppy("/api/get_user_best", peppy.GetUserBest)
 		r.Peppy("/api/get_scores", peppy.GetScores)
 		r.Peppy("/api/get_beatmaps", peppy.GetBeatmap)
+		r.Peppy("/api/get_replay", peppy.GetReplay)
 	}
 
 	// v1 API
@@ -94,8 +110,10 @@ func Start(conf common.Conf, dbO *sqlx.DB) *fhr.Router {
 		r.Method("/api/v1/surprise_me", v1.SurpriseMeGET)
 		r.Method("/api/v1/users", v1.UsersGET)
 		r.Method("/api/v1/users/whatid", v1.UserWhatsTheIDGET)
-		r.Method("/api/v1/users/full", v1.UserFullGET)
+		r.CachedMethod("/api/v1/users/full", v1.UserFullGET, CacheRule{TTL: 5 * time.Minute, UserScoped: true})
+		search.Method("/api/v1/users/history", v1.UserHistoryGET)
 		r.Method("/api/v1/users/rxfull", v1.RelaxUserFullGET)
+		r.Method("/api/v1/users/apfull", v1.AutopilotUserFullGET)
 		r.Method("/api/v1/users/achievements", v1.UserAchievementsGET)
 		r.Method("/api/v1/users/most_played", v1.UserMostPlayedGET)
 		r.Method("/api/v1/users/userpage", v1.UserUserpageGET)
@@ -103,15 +121,18 @@ func Start(conf common.Conf, dbO *sqlx.DB) *fhr.Router {
 		r.Method("/api/v1/users/scores/best", v1.UserScoresBestGET)
 		r.Method("/api/v1/users/scores/recent", v1.UserScoresRecentGET)
 		r.Method("/api/v1/users/scores/first", v1.UserFirstGET) // Thanks Akatsuki!
-		r.Method("/api/v1/badges", v1.BadgesGET)
+		r.CachedMethod("/api/v1/badges", v1.BadgesGET, CacheRule{TTL: time.Hour})
 		r.Method("/api/v1/badges/members", v1.BadgeMembersGET)
-		r.Method("/api/v1/beatmaps", v1.BeatmapGET)
-		r.Method("/api/v1/leaderboard", v1.LeaderboardGET)
+		r.CachedMethod("/api/v1/beatmaps", v1.BeatmapGET, CacheRule{TTL: 10 * time.Minute})
+		r.CachedMethod("/api/v1/leaderboard", v1.LeaderboardGET, CacheRule{TTL: time.Minute})
 		r.Method("/api/v1/tokens", v1.TokenGET)
 		r.Method("/api/v1/users/self", v1.UserSelfGET)
 		r.Method("/api/v1/tokens/self", v1.TokenSelfGET)
 		r.Method("/api/v1/blog/posts", v1.BlogPostsGET)
 		r.Method("/api/v1/scores", v1.ScoresGET)
+		search.Method("/api/v1/scores/search", v1.ScoresSearchGET)
+		replay.Method("/api/v1/scores/replay", v1.ScoreReplayGET)
+		replay.Method("/api/v1/scores/replay/full", v1.ScoreReplayFullGET)
 		r.Method("/api/v1/beatmaps/rank_requests/status", v1.BeatmapRankRequestsStatusGET)
 
 		// ReadConfidential privilege required
@@ -153,15 +174,36 @@ func Start(conf common.Conf, dbO *sqlx.DB) *fhr.Router {
 			common.PrivilegeManageUser, common.PrivilegeAPIMeta)
 	}
 
+	// v2 API
+	{
+		r.V2Method("/api/v2/users/:id", v2.UserGET)
+		r.V2Method("/api/v2/users/:id/scores/best", v2.UserScoresBestGET)
+		r.V2Method("/api/v2/users/:id/scores/recent", v2.UserScoresRecentGET)
+		r.V2Method("/api/v2/beatmaps/:id", v2.BeatmapGET)
+		r.V2Method("/api/v2/beatmaps/:id/scores", v2.BeatmapScoresGET)
+		r.V2Method("/api/v2/clans/:id/members", v2.ClanMembersGET)
+	}
+
 	// Ainu & Homura API
 	{
 		r.Method("/api/v1/users/followers", mitsuha.FollowersGetResponse)
 		r.Method("/api/v1/clans", v1.ClansGET)
 		r.Method("/api/v1/clans/members", v1.ClanMembersGET)
-		r.Method("/api/v1/clans/stats", v1.TotalClanStatsGET)
-		r.Method("/api/v1/clans/stats/all", v1.AllClanStatsGET)
+		r.CachedMethod("/api/v1/clans/stats", v1.TotalClanStatsGET, CacheRule{TTL: 5 * time.Minute})
+		r.CachedMethod("/api/v1/clans/stats/all", v1.AllClanStatsGET, CacheRule{TTL: 5 * time.Minute})
 		r.Method("/api/v1/clans/getinvite", v1.ClanInviteGET)
 		r.Method("/api/v1/clans/isclan", v1.IsInClanGET)
+		search.Method("/api/v1/clans/scores/best", v1.ClanScoresBestGET)
+		search.Method("/api/v1/clans/activity", v1.ClanActivityGET)
+		search.Method("/api/v1/clans/compare", v1.ClanCompareGET)
+		r.POSTMethod("/api/v1/clans/manage/create", v1.ClanManageCreatePOST, common.PrivilegeWrite)
+		r.POSTMethod("/api/v1/clans/manage/edit", v1.ClanManageEditPOST, common.PrivilegeWrite)
+		r.POSTMethod("/api/v1/clans/manage/kick", v1.ClanManageKickPOST, common.PrivilegeWrite)
+		r.POSTMethod("/api/v1/clans/manage/transfer", v1.ClanManageTransferPOST, common.PrivilegeWrite)
+		r.POSTMethod("/api/v1/clans/manage/perms", v1.ClanManagePermsPOST, common.PrivilegeWrite)
+		r.POSTMethod("/api/v1/clans/manage/invite", v1.ClanManageInvitePOST, common.PrivilegeWrite)
+		r.POSTMethod("/api/v1/clans/join", v1.ClanJoinPOST, common.PrivilegeWrite)
+		r.POSTMethod("/api/v1/clans/leave", v1.ClanLeavePOST, common.PrivilegeWrite)
 		r.Method("/api/v1/hmrapi/topdonors", hmrapi.TopDonorsGET)
 		r.Method("/api/v1/hmrapi/top_beatmaps", hmrapi.Beatmaps5GET)
 		r.Method("/api/v1/hmrapi/top_plays", hmrapi.TopPlaysGET)
@@ -182,6 +224,7 @@ func Start(conf common.Conf, dbO *sqlx.DB) *fhr.Router {
 		r.Peppy("/api/v1/get_user_best", peppy.GetUserBest)
 		r.Peppy("/api/v1/get_scores", peppy.GetScores)
 		r.Peppy("/api/v1/get_beatmaps", peppy.GetBeatmap)
+		r.Peppy("/api/v1/get_replay", peppy.GetReplay)
 	}
 
 	r.GET("/api/status", internals.Status)
@@ -4,8 +4,6 @@ import (
 	"database/sql"
 	"fmt"
 	"github.com/osu-datenshi/api/common"
-	"sort"
-	"strconv"
 )
 
 type singleClan struct {
@@ -58,346 +56,6 @@ type clanMembersData struct {
 	Members []userNotFullResponse `json:"members"`
 }
 
-// get total stats of clan. later.
-type totalStats struct {
-	common.ResponseBase
-	ClanID     int      `json:"id"`
-	ChosenMode modeData `json:"chosen_mode"`
-	Rank       int      `json:"rank"`
-}
-type clanLbSingle struct {
-	ID          int      `json:"id,omitempty"`
-	Name        string   `json:"name"`
-	Description string   `json:"description"`
-	Tag         string   `json:"tag"`
-	Icon        string   `json:"icon"`
-	ChosenMode  modeData `json:"chosen_mode"`
-	Rank        int      `json:"rank"`
-}
-
-type megaStats struct {
-	common.ResponseBase
-	Clans []clanLbSingle `json:"clans"`
-}
-
-func AllClanStatsGET(md common.MethodData) common.CodeMessager {
-	var (
-		r    megaStats
-		rows *sql.Rows
-		err  error
-	)
-		rows, err = md.DB.Query("SELECT id, name, description, icon FROM clans")
-	
-	if err != nil {
-		fmt.Println("lol")
-		md.Err(err)
-	}
-	defer rows.Close()
-	for rows.Next() {
-		nc := clanLbSingle{}
-		err = rows.Scan(&nc.ID, &nc.Name, &nc.Description, &nc.Icon)
-		if err != nil {
-			md.Err(err)
-		}
-		nc.ChosenMode.PP = 0
-		r.Clans = append(r.Clans, nc)
-	}
-	if err := rows.Err(); err != nil {
-		fmt.Println("lol 2")
-		md.Err(err)
-	}
-	r.ResponseBase.Code = 200
-	// anyone who ever looks into this, yes, i need to kill myself. ~Flame
-	m, brr := strconv.ParseInt(string(md.Query("m")[19]), 10, 64)
-	
-	if brr != nil {
-		fmt.Println("lol 3")
-		fmt.Println(brr)
-		m = 0
-	}
-	n := "std"
-	if m == 1 {
-		n = "taiko"
-	} else if m == 2 {
-		n = "ctb"
-	} else if m == 3 {
-		n = "mania"
-	} else {
-		n = "std"
-	}
-	fmt.Println(n)
-
-	for i := 0; i < len(r.Clans); i++ {
-		var members clanMembersData
-
-		rid := r.Clans[i].ID
-
-		err := md.DB.Select(&members.Members, `
-SELECT users.id,
-       users.username,
-       users.register_datetime,
-       users.privileges,
-       latest_activity,
-       mixed_stats.*
-FROM user_clans uc
-         INNER JOIN users ON users.id = uc.user
-         INNER JOIN (
-    SELECT us.id,
-           us.username_aka,
-           us.country,
-           us.user_color,
-           (us.ranked_score_std + rs.ranked_score_std)           ranked_score_std,
-           (us.total_score_std + rs.total_score_std)             total_score_std,
-           ROUND((us.pp_std + rs.pp_std) / 2)                    pp_std,
-           (us.playcount_std + rs.playcount_std)                 playcount_std,
-           (us.replays_watched_std + rs.replays_watched_std)     replays_watched_std,
-           (us.total_hits_std + rs.total_hits_std)               total_hits_std,
-           (us.ranked_score_taiko + rs.ranked_score_taiko)       ranked_score_taiko,
-           (us.total_score_taiko + rs.total_score_taiko)         total_score_taiko,
-           ROUND((us.pp_taiko + rs.pp_taiko) / 2)                pp_taiko,
-           (us.playcount_taiko + rs.playcount_taiko)             playcount_taiko,
-           (us.replays_watched_taiko + rs.replays_watched_taiko) replays_watched_taiko,
-           (us.total_hits_taiko + rs.total_hits_taiko)           total_hits_taiko,
-           (us.ranked_score_ctb + rs.ranked_score_ctb)           ranked_score_ctb,
-           (us.total_score_ctb + rs.total_score_ctb)             total_score_ctb,
-           ROUND((us.pp_ctb + rs.pp_ctb) / 2)                    pp_ctb,
-           (us.playcount_ctb + rs.playcount_ctb)                 playcount_ctb,
-           (us.replays_watched_ctb + rs.replays_watched_ctb)     replays_watched_ctb,
-           (us.total_hits_ctb + rs.total_hits_ctb)               total_hits_ctb,
-           (us.ranked_score_mania + rs.ranked_score_mania)       ranked_score_mania,
-           (us.total_score_mania + rs.total_score_mania)         total_score_mania,
-           (us.pp_mania + rs.pp_mania)                           pp_mania,
-           (us.playcount_mania + rs.playcount_mania)             playcount_mania,
-           (us.replays_watched_mania + rs.replays_watched_mania) replays_watched_mania,
-           (us.total_hits_mania + rs.total_hits_mania)           total_hits_mania
-    FROM users_stats us
-             INNER JOIN rx_stats rs on us.id = rs.id
-) mixed_stats ON mixed_stats.id = uc.user
-WHERE clan = ?
-  AND privileges & 1 = 1
-		`, rid)
-
-		if err != nil {
-			fmt.Println("lol 4")
-			fmt.Println(err)
-		}
-
-		members.Code = 200
-
-		if n == "std" {
-			fmt.Printf("%v", r.Clans)
-			for u := 0; u < len(members.Members); u++ {
-				r.Clans[i].ChosenMode.PP = r.Clans[i].ChosenMode.PP + members.Members[u].PpStd
-				r.Clans[i].ChosenMode.RankedScore = r.Clans[i].ChosenMode.RankedScore + members.Members[u].RankedScoreStd
-				r.Clans[i].ChosenMode.TotalScore = r.Clans[i].ChosenMode.TotalScore + members.Members[u].TotalScoreStd
-				r.Clans[i].ChosenMode.PlayCount = r.Clans[i].ChosenMode.PlayCount + members.Members[u].PlaycountStd
-			}
-		} else if n == "taiko" {
-			for u := 0; u < len(members.Members); u++ {
-				r.Clans[i].ChosenMode.PP = r.Clans[i].ChosenMode.PP + members.Members[u].PpTaiko
-				r.Clans[i].ChosenMode.RankedScore = r.Clans[i].ChosenMode.RankedScore + members.Members[u].RankedScoreTaiko
-				r.Clans[i].ChosenMode.TotalScore = r.Clans[i].ChosenMode.TotalScore + members.Members[u].TotalScoreTaiko
-				r.Clans[i].ChosenMode.PlayCount = r.Clans[i].ChosenMode.PlayCount + members.Members[u].PlaycountTaiko
-			}
-		} else if n == "ctb" {
-			for u := 0; u < len(members.Members); u++ {
-				r.Clans[i].ChosenMode.PP = r.Clans[i].ChosenMode.PP + members.Members[u].PpCtb
-				r.Clans[i].ChosenMode.RankedScore = r.Clans[i].ChosenMode.RankedScore + members.Members[u].RankedScoreCtb
-				r.Clans[i].ChosenMode.TotalScore = r.Clans[i].ChosenMode.TotalScore + members.Members[u].TotalScoreCtb
-				r.Clans[i].ChosenMode.PlayCount = r.Clans[i].ChosenMode.PlayCount + members.Members[u].PlaycountCtb
-			}
-		} else if n == "mania" {
-			for u := 0; u < len(members.Members); u++ {
-				r.Clans[i].ChosenMode.PP = r.Clans[i].ChosenMode.PP + members.Members[u].PpMania
-				r.Clans[i].ChosenMode.RankedScore = r.Clans[i].ChosenMode.RankedScore + members.Members[u].RankedScoreMania
-				r.Clans[i].ChosenMode.TotalScore = r.Clans[i].ChosenMode.TotalScore + members.Members[u].TotalScoreMania
-				r.Clans[i].ChosenMode.PlayCount = r.Clans[i].ChosenMode.PlayCount + members.Members[u].PlaycountMania
-			}
-		}
-		r.Clans[i].ChosenMode.PP = (r.Clans[i].ChosenMode.PP / (len(members.Members) + 1))
-	}
-
-	sort.Slice(r.Clans, func(i, j int) bool {
-		return r.Clans[i].ChosenMode.PP > r.Clans[j].ChosenMode.PP
-	})
-
-	for i := 0; i < len(r.Clans); i++ {
-		r.Clans[i].Rank = i + 1
-	}
-
-	return r
-}
-
-func TotalClanStatsGET(md common.MethodData) common.CodeMessager {
-	var (
-		r    megaStats
-		rows *sql.Rows
-		err  error
-	)
-	rows, err = md.DB.Query("SELECT id, name, description, icon FROM clans")
-
-	if err != nil {
-		md.Err(err)
-		return Err500
-	}
-	defer rows.Close()
-	for rows.Next() {
-		nc := clanLbSingle{}
-		err = rows.Scan(&nc.ID, &nc.Name, &nc.Description, &nc.Icon)
-		if err != nil {
-			md.Err(err)
-		}
-		nc.ChosenMode.PP = 0
-		r.Clans = append(r.Clans, nc)
-	}
-	if err := rows.Err(); err != nil {
-		md.Err(err)
-	}
-	r.ResponseBase.Code = 200
-
-	id := common.Int(md.Query("id"))
-	if id == 0 {
-		return ErrMissingField("id")
-	}
-	//Uh... well... ;-;
-	m, brr := strconv.ParseInt(string(md.Query("m")[11]), 10, 64)
-	if brr != nil {
-		fmt.Println(brr)
-	}
-
-	n := "std"
-	if m == 1 {
-		n = "taiko"
-	} else if m == 2 {
-		n = "ctb"
-	} else if m == 3 {
-		n = "mania"
-	} else {
-		n = "std"
-	}
-	fmt.Println(n)
-
-	for i := 0; i < len(r.Clans); i++ {
-		var members clanMembersData
-
-		rid := r.Clans[i].ID
-
-		err := md.DB.Select(&members.Members, `
-SELECT users.id,
-       users.username,
-       users.register_datetime,
-       users.privileges,
-       latest_activity,
-       mixed_stats.*
-FROM user_clans uc
-         INNER JOIN users ON users.id = uc.user
-         INNER JOIN (
-    SELECT us.id,
-           us.username_aka,
-           us.country,
-           us.user_color,
-           (us.ranked_score_std + rs.ranked_score_std)           ranked_score_std,
-           (us.total_score_std + rs.total_score_std)             total_score_std,
-           ROUND((us.pp_std + rs.pp_std) / 2)                    pp_std,
-           (us.playcount_std + rs.playcount_std)                 playcount_std,
-           (us.replays_watched_std + rs.replays_watched_std)     replays_watched_std,
-           (us.total_hits_std + rs.total_hits_std)               total_hits_std,
-           (us.ranked_score_taiko + rs.ranked_score_taiko)       ranked_score_taiko,
-           (us.total_score_taiko + rs.total_score_taiko)         total_score_taiko,
-           ROUND((us.pp_taiko + rs.pp_taiko) / 2)                pp_taiko,
-           (us.playcount_taiko + rs.playcount_taiko)             playcount_taiko,
-           (us.replays_watched_taiko + rs.replays_watched_taiko) replays_watched_taiko,
-           (us.total_hits_taiko + rs.total_hits_taiko)           total_hits_taiko,
-           (us.ranked_score_ctb + rs.ranked_score_ctb)           ranked_score_ctb,
-           (us.total_score_ctb + rs.total_score_ctb)             total_score_ctb,
-           ROUND((us.pp_ctb + rs.pp_ctb) / 2)                    pp_ctb,
-           (us.playcount_ctb + rs.playcount_ctb)                 playcount_ctb,
-           (us.replays_watched_ctb + rs.replays_watched_ctb)     replays_watched_ctb,
-           (us.total_hits_ctb + rs.total_hits_ctb)               total_hits_ctb,
-           (us.ranked_score_mania + rs.ranked_score_mania)       ranked_score_mania,
-           (us.total_score_mania + rs.total_score_mania)         total_score_mania,
-           (us.pp_mania + rs.pp_mania)                           pp_mania,
-           (us.playcount_mania + rs.playcount_mania)             playcount_mania,
-           (us.replays_watched_mania + rs.replays_watched_mania) replays_watched_mania,
-           (us.total_hits_mania + rs.total_hits_mania)           total_hits_mania
-    FROM users_stats us
-             INNER JOIN rx_stats rs on us.id = rs.id
-) mixed_stats ON mixed_stats.id = uc.user
-WHERE clan = ?
-  AND privileges & 1 = 1
-		`, rid)
-
-		if err != nil {
-			fmt.Println(err)
-		}
-
-		members.Code = 200
-
-		if n == "std" {
-			for u := 0; u < len(members.Members); u++ {
-				r.Clans[i].ChosenMode.PP = r.Clans[i].ChosenMode.PP + members.Members[u].PpStd
-				r.Clans[i].ChosenMode.RankedScore = r.Clans[i].ChosenMode.RankedScore + members.Members[u].RankedScoreStd
-				r.Clans[i].ChosenMode.TotalScore = r.Clans[i].ChosenMode.TotalScore + members.Members[u].TotalScoreStd
-				r.Clans[i].ChosenMode.PlayCount = r.Clans[i].ChosenMode.PlayCount + members.Members[u].PlaycountStd
-				r.Clans[i].ChosenMode.ReplaysWatched = r.Clans[i].ChosenMode.ReplaysWatched + members.Members[u].ReplaysWatchedStd
-				r.Clans[i].ChosenMode.TotalHits = r.Clans[i].ChosenMode.TotalHits + members.Members[u].TotalHitsStd
-			}
-		} else if n == "taiko" {
-			for u := 0; u < len(members.Members); u++ {
-				r.Clans[i].ChosenMode.PP = r.Clans[i].ChosenMode.PP + members.Members[u].PpTaiko
-				r.Clans[i].ChosenMode.RankedScore = r.Clans[i].ChosenMode.RankedScore + members.Members[u].RankedScoreTaiko
-				r.Clans[i].ChosenMode.TotalScore = r.Clans[i].ChosenMode.TotalScore + members.Members[u].TotalScoreTaiko
-				r.Clans[i].ChosenMode.PlayCount = r.Clans[i].ChosenMode.PlayCount + members.Members[u].PlaycountTaiko
-				r.Clans[i].ChosenMode.ReplaysWatched = r.Clans[i].ChosenMode.ReplaysWatched + members.Members[u].ReplaysWatchedTaiko
-				r.Clans[i].ChosenMode.TotalHits = r.Clans[i].ChosenMode.TotalHits + members.Members[u].TotalHitsTaiko
-			}
-		} else if n == "ctb" {
-			for u := 0; u < len(members.Members); u++ {
-				r.Clans[i].ChosenMode.PP = r.Clans[i].ChosenMode.PP + members.Members[u].PpCtb
-				r.Clans[i].ChosenMode.RankedScore = r.Clans[i].ChosenMode.RankedScore + members.Members[u].RankedScoreCtb
-				r.Clans[i].ChosenMode.TotalScore = r.Clans[i].ChosenMode.TotalScore + members.Members[u].TotalScoreCtb
-				r.Clans[i].ChosenMode.PlayCount = r.Clans[i].ChosenMode.PlayCount + members.Members[u].PlaycountCtb
-				r.Clans[i].ChosenMode.ReplaysWatched = r.Clans[i].ChosenMode.ReplaysWatched + members.Members[u].ReplaysWatchedCtb
-				r.Clans[i].ChosenMode.TotalHits = r.Clans[i].ChosenMode.TotalHits + members.Members[u].TotalHitsStd
-			}
-		} else if n == "mania" {
-			for u := 0; u < len(members.Members); u++ {
-				r.Clans[i].ChosenMode.PP = r.Clans[i].ChosenMode.PP + members.Members[u].PpMania
-				r.Clans[i].ChosenMode.RankedScore = r.Clans[i].ChosenMode.RankedScore + members.Members[u].RankedScoreMania
-				r.Clans[i].ChosenMode.TotalScore = r.Clans[i].ChosenMode.TotalScore + members.Members[u].TotalScoreMania
-				r.Clans[i].ChosenMode.PlayCount = r.Clans[i].ChosenMode.PlayCount + members.Members[u].PlaycountMania
-				r.Clans[i].ChosenMode.ReplaysWatched = r.Clans[i].ChosenMode.ReplaysWatched + members.Members[u].ReplaysWatchedMania
-				r.Clans[i].ChosenMode.TotalHits = r.Clans[i].ChosenMode.TotalHits + members.Members[u].TotalHitsMania
-			}
-		}
-		r.Clans[i].ChosenMode.PP = (r.Clans[i].ChosenMode.PP / (len(members.Members) + 1))
-	}
-
-	sort.Slice(r.Clans, func(i, j int) bool {
-		return r.Clans[i].ChosenMode.PP > r.Clans[j].ChosenMode.PP
-	})
-
-	for i := 0; i < len(r.Clans); i++ {
-		r.Clans[i].Rank = i + 1
-	}
-	b := totalStats{}
-	for i := 0; i < len(r.Clans); i++ {
-		if r.Clans[i].ID == id {
-			b.ClanID = id
-			b.ChosenMode.PP = r.Clans[i].ChosenMode.PP
-			b.ChosenMode.RankedScore = r.Clans[i].ChosenMode.RankedScore
-			b.ChosenMode.TotalScore = r.Clans[i].ChosenMode.TotalScore
-			b.ChosenMode.PlayCount = r.Clans[i].ChosenMode.PlayCount
-			b.ChosenMode.ReplaysWatched = r.Clans[i].ChosenMode.ReplaysWatched
-			b.ChosenMode.TotalHits = r.Clans[i].ChosenMode.TotalHits
-			b.Rank = r.Clans[i].Rank
-			b.Code = 200
-		}
-	}
-
-	return b
-}
-
 type isClanData struct {
 	Clan  int `json:"clan"`
 	User  int `json:"user"`
@@ -0,0 +1,136 @@
+package v1
+
+import (
+	"database/sql"
+	"sort"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/osu-datenshi/api/common"
+)
+
+type clanComparison struct {
+	singleClan
+	clanTotals
+	// Wins is the number of beatmaps on which the clan holds the best score.
+	Wins int `json:"wins"`
+}
+
+type clanCompareBeatmap struct {
+	BeatmapID int    `json:"beatmap_id"`
+	SongName  string `json:"song_name"`
+	ScoreA    int64  `json:"score_a"`
+	ScoreB    int64  `json:"score_b"`
+	// Winner is the ID of the clan with the best score, or 0 on a tie.
+	Winner int `json:"winner"`
+}
+
+type clanCompareResponse struct {
+	common.ResponseBase
+	Mode        int                  `json:"mode"`
+	SpecialMode int                  `json:"smode"`
+	A           clanComparison       `json:"a"`
+	B           clanComparison       `json:"b"`
+	Beatmaps    []clanCompareBeatmap `json:"beatmaps"`
+}
+
+// ClanCompareGET compares two clans in a mode: their total stats, and on the
+// beatmaps that members of both clans have played, which clan has the best
+// score.
+func ClanCompareGET(md common.MethodData) common.CodeMessager {
+	a, b := common.Int(md.Query("a")), common.Int(md.Query("b"))
+	switch {
+	case a == 0:
+		return ErrMissingField("a")
+	case b == 0:
+		return ErrMissingField("b")
+	case a == b:
+		return common.SimpleResponse(400, "You can't compare a clan with itself.")
+	}
+	mode := common.Int(md.Query("mode"))
+	if mode < 0 || mode > 3 {
+		mode = 0
+	}
+	sm := md.SpecialMode()
+
+	r := clanCompareResponse{Mode: mode, SpecialMode: sm.ID, Beatmaps: []clanCompareBeatmap{}}
+	for _, c := range [...]struct {
+		id int
+		to *clanComparison
+	}{{a, &r.A}, {b, &r.B}} {
+		err := md.DB.QueryRow("SELECT id, name, description, tag, icon FROM clans WHERE id = ? LIMIT 1", c.id).Scan(
+			&c.to.ID, &c.to.Name, &c.to.Description, &c.to.Tag, &c.to.Icon)
+		switch {
+		case err == sql.ErrNoRows:
+			return common.SimpleResponse(404, "That clan could not be found!")
+		case err != nil:
+			md.Err(err)
+			return Err500
+		}
+		c.to.clanTotals, err = getClanTotals(md.DB, c.id, mode, sm)
+		if err != nil {
+			md.Err(err)
+			return Err500
+		}
+	}
+
+	// best score of each clan on every beatmap played by any of the two.
+	query, params, _ := sqlx.In(`SELECT
+			b.beatmap_id, b.song_name, uc.clan, MAX(s.score)
+		FROM scores_master as s
+		INNER JOIN beatmaps as b ON b.beatmap_md5 = s.beatmap_md5
+		INNER JOIN users ON users.id = s.userid
+		INNER JOIN user_clans as uc ON uc.user = s.userid
+		WHERE uc.clan IN (?) AND s.completed = '3' AND s.play_mode = ? AND `+sm.ScoresFilter("s")+`
+			AND users.privileges & 1 = 1
+		GROUP BY b.beatmap_id, b.song_name, uc.clan`, []int{a, b}, mode)
+	rows, err := md.DB.Query(query, params...)
+	if err != nil {
+		md.Err(err)
+		return Err500
+	}
+	defer rows.Close()
+	beatmaps := make(map[int]*clanCompareBeatmap)
+	for rows.Next() {
+		var (
+			bm    clanCompareBeatmap
+			clan  int
+			score int64
+		)
+		if err := rows.Scan(&bm.BeatmapID, &bm.SongName, &clan, &score); err != nil {
+			md.Err(err)
+			return Err500
+		}
+		if beatmaps[bm.BeatmapID] == nil {
+			beatmaps[bm.BeatmapID] = &bm
+		}
+		if clan == a {
+			beatmaps[bm.BeatmapID].ScoreA = score
+		} else {
+			beatmaps[bm.BeatmapID].ScoreB = score
+		}
+	}
+	if err := rows.Err(); err != nil {
+		md.Err(err)
+		return Err500
+	}
+
+	for _, bm := range beatmaps {
+		if bm.ScoreA == 0 || bm.ScoreB == 0 {
+			continue
+		}
+		switch {
+		case bm.ScoreA > bm.ScoreB:
+			bm.Winner = a
+			r.A.Wins++
+		case bm.ScoreB > bm.ScoreA:
+			bm.Winner = b
+			r.B.Wins++
+		}
+		r.Beatmaps = append(r.Beatmaps, *bm)
+	}
+	sort.Slice(r.Beatmaps, func(i, j int) bool {
+		return r.Beatmaps[i].BeatmapID < r.Beatmaps[j].BeatmapID
+	})
+	r.Code = 200
+	return r
+}
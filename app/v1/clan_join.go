@@ -0,0 +1,158 @@
+package v1
+
+import (
+	"database/sql"
+	"strconv"
+
+	"github.com/osu-datenshi/api/common"
+	"gopkg.in/redis.v5"
+)
+
+// updateClanBancho tells the game server that the clan of an user has
+// changed, so that it can update their clan tag.
+func updateClanBancho(r *redis.Client, user int) error {
+	return r.Publish("peppy:update_clan", strconv.Itoa(user)).Err()
+}
+
+// clanMaxMembers returns the maximum number of members a clan can have.
+func clanMaxMembers() int {
+	if c := common.GetConf(); c != nil && c.ClanMaxMembers > 0 {
+		return c.ClanMaxMembers
+	}
+	return common.DefaultClanMaxMembers
+}
+
+// ClanJoinPOST makes the current user join the clan of an invite code.
+func ClanJoinPOST(md common.MethodData) common.CodeMessager {
+	var d struct {
+		Invite string `json:"invite"`
+	}
+	if err := md.Unmarshal(&d); err != nil {
+		return ErrBadJSON
+	}
+	if d.Invite == "" {
+		return ErrMissingField("invite")
+	}
+
+	current, _, err := clanMembership(md)
+	if err != nil {
+		md.Err(err)
+		return Err500
+	}
+	if current != 0 {
+		return common.SimpleResponse(409, "You are already in a clan. Leave it first!")
+	}
+
+	var clan int
+	err = md.DB.QueryRow("SELECT clan FROM clans_invites WHERE invite = ? LIMIT 1", d.Invite).Scan(&clan)
+	switch {
+	case err == sql.ErrNoRows:
+		return common.SimpleResponse(404, "That invite is not valid.")
+	case err != nil:
+		md.Err(err)
+		return Err500
+	}
+
+	// The row of the clan is locked until the user has joined, so that two
+	// users can't both take the last place in it.
+	tx, err := md.DB.Begin()
+	if err != nil {
+		md.Err(err)
+		return Err500
+	}
+	var members int
+	err = tx.QueryRow("SELECT id FROM clans WHERE id = ? FOR UPDATE", clan).Scan(&clan)
+	if err == nil {
+		err = tx.QueryRow("SELECT COUNT(*) FROM user_clans WHERE clan = ?", clan).Scan(&members)
+	}
+	switch {
+	case err == sql.ErrNoRows:
+		tx.Rollback()
+		return common.SimpleResponse(404, "That invite is not valid.")
+	case err != nil:
+		tx.Rollback()
+		md.Err(err)
+		return Err500
+	case members >= clanMaxMembers():
+		tx.Rollback()
+		return common.SimpleResponse(403, "That clan is full.")
+	}
+	_, err = tx.Exec("INSERT INTO user_clans(user, clan, perms) VALUES (?, ?, ?)", md.ID(), clan, clanPermsMember)
+	if err != nil {
+		tx.Rollback()
+		if _, dup := common.DuplicateKey(err); dup {
+			return common.SimpleResponse(409, "You are already in a clan. Leave it first!")
+		}
+		md.Err(err)
+		return Err500
+	}
+	if err = tx.Commit(); err != nil {
+		md.Err(err)
+		return Err500
+	}
+	if err := updateClanBancho(md.R, md.ID()); err != nil {
+		md.Err(err)
+	}
+	updateClanLeaderboard(md, clan)
+	return getClan(md, clan)
+}
+
+// ClanLeavePOST makes the current user leave their clan. The owner has to
+// transfer the clan to another member before leaving, unless they are the
+// last member, in which case the clan is disbanded.
+func ClanLeavePOST(md common.MethodData) common.CodeMessager {
+	clan, perms, resp := clanManager(md, clanPermsMember)
+	if resp != nil {
+		return resp
+	}
+
+	// As when joining, the row of the clan is locked, so that the members
+	// are still the ones counted when the clan is disbanded.
+	tx, err := md.DB.Begin()
+	if err != nil {
+		md.Err(err)
+		return Err500
+	}
+	var members int
+	err = tx.QueryRow("SELECT id FROM clans WHERE id = ? FOR UPDATE", clan).Scan(&clan)
+	if err == nil {
+		err = tx.QueryRow("SELECT COUNT(*) FROM user_clans WHERE clan = ?", clan).Scan(&members)
+	}
+	switch {
+	case err == sql.ErrNoRows:
+		tx.Rollback()
+		return common.SimpleResponse(404, "You are not in a clan.")
+	case err != nil:
+		tx.Rollback()
+		md.Err(err)
+		return Err500
+	case perms >= clanPermsOwner && members > 1:
+		tx.Rollback()
+		return common.SimpleResponse(403, "You own the clan: transfer it to another member before leaving.")
+	}
+	_, err = tx.Exec("DELETE FROM user_clans WHERE user = ? AND clan = ?", md.ID(), clan)
+	if err == nil && members == 1 {
+		_, err = tx.Exec("DELETE FROM clans_invites WHERE clan = ?", clan)
+		if err == nil {
+			_, err = tx.Exec("DELETE FROM clans WHERE id = ? LIMIT 1", clan)
+		}
+	}
+	if err != nil {
+		tx.Rollback()
+		md.Err(err)
+		return Err500
+	}
+	if err = tx.Commit(); err != nil {
+		md.Err(err)
+		return Err500
+	}
+
+	if err := updateClanBancho(md.R, md.ID()); err != nil {
+		md.Err(err)
+	}
+	updateClanLeaderboard(md, clan)
+	if members == 1 {
+		return common.SimpleResponse(200, "You left the clan, which has been disbanded.")
+	}
+	return common.SimpleResponse(200, "You left the clan.")
+}
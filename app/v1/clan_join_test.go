@@ -0,0 +1,139 @@
+package v1
+
+import (
+	"database/sql/driver"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/osu-datenshi/api/common"
+	"github.com/osu-datenshi/api/internal/fakesql"
+	"github.com/valyala/fasthttp"
+	"gopkg.in/redis.v5"
+)
+
+// testClanMethod calls a clan method as the user 1000, who has perms in the
+// clan 7 of 2 members, and returns its response, the statements it ran and
+// the users published on peppy:update_clan.
+func testClanMethod(t *testing.T, f func(common.MethodData) common.CodeMessager,
+	body string, perms int, extra ...fakesql.Result) (common.CodeMessager, []fakesql.Query, []string) {
+	s, err := miniredis.Run()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+	r := redis.NewClient(&redis.Options{Addr: s.Addr()})
+	defer r.Close()
+	ps, err := r.Subscribe("peppy:update_clan")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ps.Close()
+	// The subscription is confirmed before anything is published, so that no
+	// message is missed.
+	if _, err := ps.ReceiveTimeout(time.Second); err != nil {
+		t.Fatal(err)
+	}
+
+	i := func(x int64) driver.Value { return x }
+	db, queries := fakesql.Open(append(extra,
+		fakesql.Result{
+			Match:   "FROM user_clans uc",
+			Columns: []string{"members", "pp", "ranked_score", "total_score", "playcount", "replays_watched", "total_hits", "accuracy"},
+			Rows:    [][]driver.Value{{i(1), i(0), i(0), i(0), i(0), i(0), i(0), 0.0}},
+		},
+		fakesql.Result{Match: "SELECT clan, perms", Columns: []string{"clan", "perms"}, Rows: [][]driver.Value{{i(7), i(int64(perms))}}},
+		fakesql.Result{Match: "SELECT perms", Columns: []string{"perms"}, Rows: [][]driver.Value{{i(clanPermsMember)}}},
+		fakesql.Result{Match: "FOR UPDATE", Columns: []string{"id"}, Rows: [][]driver.Value{{i(7)}}},
+		fakesql.Result{Match: "SELECT user FROM user_clans", Columns: []string{"user"}, Rows: [][]driver.Value{{i(1000)}, {i(1001)}}},
+		fakesql.Result{Match: "SELECT EXISTS", Columns: []string{"exists"}, Rows: [][]driver.Value{{false}}},
+		fakesql.Result{Match: "FROM clans WHERE id", Columns: []string{"id", "name", "description", "tag", "icon"},
+			Rows: [][]driver.Value{{i(7), "Clan", "", "TAG", ""}}},
+	)...)
+	defer db.Close()
+
+	var c fasthttp.RequestCtx
+	c.Request.SetBody([]byte(body))
+	resp := f(common.MethodData{Ctx: &c, DB: db, R: r, User: common.Token{UserID: 1000}})
+
+	var published []string
+	for {
+		msg, err := ps.ReceiveTimeout(100 * time.Millisecond)
+		if err != nil {
+			break
+		}
+		if m, ok := msg.(*redis.Message); ok {
+			published = append(published, m.Payload)
+		}
+	}
+	return resp, *queries, published
+}
+
+func TestClanLeavePOST(t *testing.T) {
+	tests := []struct {
+		name      string
+		perms     int
+		members   int64
+		code      int
+		published []string
+		deletes   int
+	}{
+		{"member", clanPermsMember, 2, 200, []string{"1000"}, 1},
+		{"owner", clanPermsOwner, 2, 403, nil, 0},
+		{"last member", clanPermsOwner, 1, 200, []string{"1000"}, 3},
+	}
+	for _, tt := range tests {
+		resp, queries, published := testClanMethod(t, ClanLeavePOST, "", tt.perms, fakesql.Result{
+			Match:   "SELECT COUNT(*) FROM user_clans WHERE clan",
+			Columns: []string{"members"},
+			Rows:    [][]driver.Value{{tt.members}},
+		})
+		if resp.GetCode() != tt.code {
+			t.Errorf("%q. code = %v, want %v", tt.name, resp.GetCode(), tt.code)
+		}
+		if !reflect.DeepEqual(published, tt.published) {
+			t.Errorf("%q. published %v, want %v", tt.name, published, tt.published)
+		}
+		// The clan must be locked before its members are counted.
+		var locked bool
+		deletes := 0
+		for _, q := range queries {
+			switch {
+			case strings.Contains(q.Query, "FOR UPDATE"):
+				locked = true
+			case strings.Contains(q.Query, "SELECT COUNT(*) FROM user_clans WHERE clan") && !locked:
+				t.Errorf("%q. members counted before locking the clan", tt.name)
+			case strings.HasPrefix(q.Query, "DELETE"):
+				deletes++
+			}
+		}
+		if deletes != tt.deletes {
+			t.Errorf("%q. ran %v deletes, want %v", tt.name, deletes, tt.deletes)
+		}
+	}
+}
+
+func TestClanBanchoUpdates(t *testing.T) {
+	tests := []struct {
+		name      string
+		f         func(common.MethodData) common.CodeMessager
+		body      string
+		perms     int
+		published []string
+	}{
+		{"kick", ClanManageKickPOST, `{"user": 1001}`, clanPermsOwner, []string{"1001"}},
+		{"edit tag", ClanManageEditPOST, `{"tag": "NEW"}`, clanPermsOwner, []string{"1000", "1001"}},
+		{"edit name", ClanManageEditPOST, `{"name": "New"}`, clanPermsOwner, nil},
+	}
+	for _, tt := range tests {
+		resp, _, published := testClanMethod(t, tt.f, tt.body, tt.perms)
+		if resp.GetCode() != 200 {
+			t.Errorf("%q. code = %v, want 200", tt.name, resp.GetCode())
+		}
+		if !reflect.DeepEqual(published, tt.published) {
+			t.Errorf("%q. published %v, want %v", tt.name, published, tt.published)
+		}
+	}
+}
@@ -0,0 +1,289 @@
+package v1
+
+import (
+	"database/sql"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/osu-datenshi/api/common"
+	"gopkg.in/redis.v5"
+)
+
+// clanLeaderboardKey is the sorted set ranking the clans by pp in a mode.
+func clanLeaderboardKey(sm common.SpecialMode, mode string) string {
+	return sm.Key("clan_leaderboard") + mode
+}
+
+// clanStatsKey is the hash holding the total stats of a clan in a mode.
+func clanStatsKey(sm common.SpecialMode, mode string, clan int) string {
+	return sm.Key("clan_stats") + mode + ":" + strconv.Itoa(clan)
+}
+
+// clanTotals are the stats of all the members of a clan in a mode.
+type clanTotals struct {
+	Members        int     `json:"members"`
+	PP             int64   `json:"pp"`
+	RankedScore    int64   `json:"ranked_score"`
+	TotalScore     int64   `json:"total_score"`
+	PlayCount      int64   `json:"playcount"`
+	ReplaysWatched int64   `json:"replays_watched"`
+	TotalHits      int64   `json:"total_hits"`
+	Accuracy       float64 `json:"accuracy"`
+}
+
+// getClanTotals sums up the stats of the public members of a clan in a mode.
+// The pp of the clan are the sum of the pp of its members, divided by the
+// members plus one, which keeps clans with a single strong player from
+// topping the leaderboard.
+func getClanTotals(db *sqlx.DB, clan, mode int, sm common.SpecialMode) (clanTotals, error) {
+	var t clanTotals
+	err := db.QueryRow(fmt.Sprintf(`SELECT
+			COUNT(*), IFNULL(SUM(st.pp_%[1]s), 0),
+			IFNULL(SUM(st.ranked_score_%[1]s), 0), IFNULL(SUM(st.total_score_%[1]s), 0),
+			IFNULL(SUM(st.playcount_%[1]s), 0), IFNULL(SUM(us.replays_watched_%[1]s), 0),
+			IFNULL(SUM(us.total_hits_%[1]s), 0), IFNULL(AVG(st.avg_accuracy_%[1]s), 0)
+		FROM user_clans uc
+		INNER JOIN users ON users.id = uc.user
+		INNER JOIN users_stats us ON us.id = uc.user
+		INNER JOIN %[2]s st ON st.id = uc.user
+		WHERE uc.clan = ? AND users.privileges & 1 = 1`, modesToReadable[mode], sm.StatsTable), clan).Scan(
+		&t.Members, &t.PP, &t.RankedScore, &t.TotalScore, &t.PlayCount, &t.ReplaysWatched,
+		&t.TotalHits, &t.Accuracy,
+	)
+	t.PP /= int64(t.Members + 1)
+	return t, err
+}
+
+// UpdateClanStats sums up the stats of the members of a clan in a mode, and
+// puts the clan in its place on the clan leaderboard.
+func UpdateClanStats(db *sqlx.DB, r *redis.Client, clan, mode int, sm common.SpecialMode) error {
+	m := modesToReadable[mode]
+	t, err := getClanTotals(db, clan, mode, sm)
+	if err != nil {
+		return err
+	}
+
+	lbKey, statsKey := clanLeaderboardKey(sm, m), clanStatsKey(sm, m, clan)
+	if t.Members == 0 {
+		_, err = r.Pipelined(func(p *redis.Pipeline) error {
+			p.ZRem(lbKey, strconv.Itoa(clan))
+			p.Del(statsKey)
+			return nil
+		})
+		return err
+	}
+	_, err = r.Pipelined(func(p *redis.Pipeline) error {
+		p.HMSet(statsKey, map[string]string{
+			"members":         strconv.Itoa(t.Members),
+			"pp":              strconv.FormatInt(t.PP, 10),
+			"ranked_score":    strconv.FormatInt(t.RankedScore, 10),
+			"total_score":     strconv.FormatInt(t.TotalScore, 10),
+			"playcount":       strconv.FormatInt(t.PlayCount, 10),
+			"replays_watched": strconv.FormatInt(t.ReplaysWatched, 10),
+			"total_hits":      strconv.FormatInt(t.TotalHits, 10),
+			"accuracy":        strconv.FormatFloat(t.Accuracy, 'f', -1, 64),
+		})
+		p.ZAdd(lbKey, redis.Z{Score: float64(t.PP), Member: strconv.Itoa(clan)})
+		return nil
+	})
+	return err
+}
+
+// UpdateUserClanStats updates the stats of the clan of an user, if they are
+// in one.
+func UpdateUserClanStats(db *sqlx.DB, r *redis.Client, user, mode int, sm common.SpecialMode) error {
+	var clan int
+	err := db.QueryRow("SELECT clan FROM user_clans WHERE user = ? LIMIT 1", user).Scan(&clan)
+	switch {
+	case err == sql.ErrNoRows:
+		return nil
+	case err != nil:
+		return err
+	}
+	return UpdateClanStats(db, r, clan, mode, sm)
+}
+
+// RebuildClanLeaderboard recomputes the stats of all the clans, in all the
+// modes, and drops from the leaderboards the clans that no longer exist.
+func RebuildClanLeaderboard(db *sqlx.DB, r *redis.Client) error {
+	var clans []int
+	err := db.Select(&clans, "SELECT id FROM clans")
+	if err != nil {
+		return err
+	}
+	exists := make(map[string]bool, len(clans))
+	for _, clan := range clans {
+		exists[strconv.Itoa(clan)] = true
+	}
+	for mode, m := range modesToReadable {
+		for _, sm := range common.SpecialModes {
+			for _, clan := range clans {
+				if err := UpdateClanStats(db, r, clan, mode, sm); err != nil {
+					return err
+				}
+			}
+			key := clanLeaderboardKey(sm, m)
+			ranked, err := r.ZRange(key, 0, -1).Result()
+			if err != nil {
+				return err
+			}
+			for _, clan := range ranked {
+				if !exists[clan] {
+					r.ZRem(key, clan)
+					r.Del(clanStatsKey(sm, m, common.Int(clan)))
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// LoadClanLeaderboardEvery rebuilds the clan leaderboard every given amount
+// of time, catching up with the changes that don't come with a score
+// submission, such as restrictions.
+func LoadClanLeaderboardEvery(db *sqlx.DB, r *redis.Client, d time.Duration) {
+	for {
+		err := RebuildClanLeaderboard(db, r)
+		if err != nil {
+			fmt.Println("RebuildClanLeaderboard error", err)
+			common.GenericError(err)
+		}
+		time.Sleep(d)
+	}
+}
+
+type clanStats struct {
+	ID          int      `json:"id,omitempty"`
+	Name        string   `json:"name"`
+	Description string   `json:"description"`
+	Tag         string   `json:"tag"`
+	Icon        string   `json:"icon"`
+	Members     int      `json:"members"`
+	ChosenMode  modeData `json:"chosen_mode"`
+	Rank        int      `json:"rank"`
+}
+
+type clanLeaderboardResponse struct {
+	common.ResponseBase
+	Clans []clanStats `json:"clans"`
+}
+
+// clanModeData reads the stats of a clan from redis.
+func clanModeData(r *redis.Client, sm common.SpecialMode, mode string, clan int) (modeData, int, error) {
+	var d modeData
+	h, err := r.HGetAll(clanStatsKey(sm, mode, clan)).Result()
+	if err != nil {
+		return d, 0, err
+	}
+	d.PP = common.Int(h["pp"])
+	d.RankedScore, _ = strconv.ParseInt(h["ranked_score"], 10, 64)
+	d.TotalScore, _ = strconv.ParseInt(h["total_score"], 10, 64)
+	d.PlayCount = common.Int(h["playcount"])
+	d.ReplaysWatched = common.Int(h["replays_watched"])
+	d.TotalHits = common.Int(h["total_hits"])
+	d.Accuracy, _ = strconv.ParseFloat(h["accuracy"], 64)
+	return d, common.Int(h["members"]), nil
+}
+
+// clanStatsMode returns the mode requested to the clan stats methods, in mode
+// or, as the clients used to pass it, in m.
+func clanStatsMode(md common.MethodData) string {
+	if md.HasQuery("mode") {
+		return getMode(md.Query("mode"))
+	}
+	return getMode(md.Query("m"))
+}
+
+// AllClanStatsGET retrieves the clan leaderboard of a mode.
+func AllClanStatsGET(md common.MethodData) common.CodeMessager {
+	m := clanStatsMode(md)
+	sm := md.SpecialMode()
+
+	p := common.Int(md.Query("p")) - 1
+	if p < 0 {
+		p = 0
+	}
+	l := common.InString(1, md.Query("l"), 500, 50)
+
+	ids, err := md.R.ZRevRange(clanLeaderboardKey(sm, m), int64(p*l), int64(p*l+l-1)).Result()
+	if err != nil {
+		md.Err(err)
+		return Err500
+	}
+
+	resp := clanLeaderboardResponse{Clans: []clanStats{}}
+	resp.Code = 200
+	if len(ids) == 0 {
+		return resp
+	}
+
+	query, params, _ := sqlx.In("SELECT id, name, description, tag, icon FROM clans WHERE id IN (?)", ids)
+	var clans []clanStats
+	err = md.DB.Select(&clans, query, params...)
+	if err != nil {
+		md.Err(err)
+		return Err500
+	}
+	byID := make(map[int]clanStats, len(clans))
+	for _, c := range clans {
+		byID[c.ID] = c
+	}
+
+	for i, id := range ids {
+		c, ok := byID[common.Int(id)]
+		if !ok {
+			continue
+		}
+		c.ChosenMode, c.Members, err = clanModeData(md.R, sm, m, c.ID)
+		if err != nil {
+			md.Err(err)
+			return Err500
+		}
+		c.Rank = p*l + i + 1
+		resp.Clans = append(resp.Clans, c)
+	}
+	return resp
+}
+
+type clanStatsResponse struct {
+	common.ResponseBase
+	ClanID     int      `json:"id"`
+	Members    int      `json:"members"`
+	ChosenMode modeData `json:"chosen_mode"`
+	Rank       int      `json:"rank"`
+}
+
+// TotalClanStatsGET retrieves the stats of a clan in a mode, along with its
+// position on the clan leaderboard.
+func TotalClanStatsGET(md common.MethodData) common.CodeMessager {
+	id := common.Int(md.Query("id"))
+	if id == 0 {
+		return ErrMissingField("id")
+	}
+	m := clanStatsMode(md)
+	sm := md.SpecialMode()
+
+	var exists bool
+	err := md.DB.QueryRow("SELECT EXISTS(SELECT 1 FROM clans WHERE id = ?)", id).Scan(&exists)
+	if err != nil {
+		md.Err(err)
+		return Err500
+	}
+	if !exists {
+		return common.SimpleResponse(404, "That clan could not be found!")
+	}
+
+	r := clanStatsResponse{ClanID: id}
+	r.ChosenMode, r.Members, err = clanModeData(md.R, sm, m, id)
+	if err != nil {
+		md.Err(err)
+		return Err500
+	}
+	if i := _position(md.R, clanLeaderboardKey(sm, m), id); i != nil {
+		r.Rank = *i
+	}
+	r.Code = 200
+	return r
+}
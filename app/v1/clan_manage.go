@@ -0,0 +1,482 @@
+package v1
+
+import (
+	"crypto/rand"
+	"database/sql"
+	"encoding/hex"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/osu-datenshi/api/common"
+)
+
+// Permission levels of the members of a clan, stored in user_clans.perms.
+const (
+	clanPermsMember  = 1
+	clanPermsOfficer = 4
+	clanPermsOwner   = 8
+)
+
+// clanInviteLength is the length of the invite codes of the clans.
+const clanInviteLength = 8
+
+// newClanInvite generates a random invite code. As the code alone lets anyone
+// join the clan, it comes from crypto/rand.
+func newClanInvite() (string, error) {
+	b := make([]byte, clanInviteLength/2)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
+
+// setClanInvite gives a clan a new invite code, and returns it. In the
+// unlikely case that the code is already used by another clan, another one is
+// generated.
+func setClanInvite(db sqlx.Execer, clan int) (invite string, err error) {
+	for i := 0; i < 3; i++ {
+		invite, err = newClanInvite()
+		if err != nil {
+			return "", err
+		}
+		var res sql.Result
+		res, err = db.Exec("UPDATE clans_invites SET invite = ? WHERE clan = ?", invite, clan)
+		if err == nil {
+			if n, _ := res.RowsAffected(); n == 0 {
+				_, err = db.Exec("INSERT INTO clans_invites(clan, invite) VALUES (?, ?)", clan, invite)
+			}
+		}
+		if key, dup := common.DuplicateKey(err); !dup || key != "invite" {
+			return invite, err
+		}
+	}
+	return "", err
+}
+
+// clanMembership retrieves the clan of the current user and their permission
+// level in it. If the user is not in a clan, clan is 0.
+func clanMembership(md common.MethodData) (clan, perms int, err error) {
+	err = md.DB.QueryRow("SELECT clan, perms FROM user_clans WHERE user = ? LIMIT 1", md.ID()).Scan(&clan, &perms)
+	if err == sql.ErrNoRows {
+		err = nil
+	}
+	return
+}
+
+// clanManager makes sure the current user is in a clan and has at least the
+// given permission level in it, and returns the clan and their level.
+// If they don't, the response to send back is returned instead.
+func clanManager(md common.MethodData, minPerms int) (clan, perms int, resp common.CodeMessager) {
+	clan, perms, err := clanMembership(md)
+	switch {
+	case err != nil:
+		md.Err(err)
+		return 0, 0, Err500
+	case clan == 0:
+		return 0, 0, common.SimpleResponse(404, "You are not in a clan.")
+	case perms < minPerms:
+		return 0, 0, common.SimpleResponse(403, "You don't have the permission to do that in your clan.")
+	}
+	return clan, perms, nil
+}
+
+// clanMemberPerms retrieves the permission level of an user in a clan. ok is
+// false if they are not a member of it.
+func clanMemberPerms(md common.MethodData, clan, user int) (perms int, ok bool, err error) {
+	err = md.DB.QueryRow("SELECT perms FROM user_clans WHERE user = ? AND clan = ? LIMIT 1", user, clan).Scan(&perms)
+	switch err {
+	case nil:
+		return perms, true, nil
+	case sql.ErrNoRows:
+		return 0, false, nil
+	}
+	return 0, false, err
+}
+
+// updateClanLeaderboard updates the stats of a clan in all the modes, after
+// its members have changed.
+func updateClanLeaderboard(md common.MethodData, clan int) {
+	for mode := range modesToReadable {
+		for _, sm := range common.SpecialModes {
+			if err := UpdateClanStats(md.DB, md.R, clan, mode, sm); err != nil {
+				md.Err(err)
+				return
+			}
+		}
+	}
+}
+
+type clanData struct {
+	Name        *string `json:"name"`
+	Tag         *string `json:"tag"`
+	Description *string `json:"description"`
+	Icon        *string `json:"icon"`
+}
+
+// sanitise cleans up the data of the clan, and returns a response explaining
+// what's wrong with it if it's not valid.
+func (d *clanData) sanitise() *common.CodeMessager {
+	for _, s := range [...]*string{d.Name, d.Tag, d.Description, d.Icon} {
+		if s != nil {
+			*s = strings.TrimSpace(common.SanitiseString(*s))
+		}
+	}
+	var r common.CodeMessager
+	switch {
+	case d.Name != nil && (*d.Name == "" || utf8.RuneCountInString(*d.Name) > 32):
+		r = common.SimpleResponse(400, "The name of the clan must be between 1 and 32 characters long.")
+	case d.Tag != nil && (*d.Tag == "" || utf8.RuneCountInString(*d.Tag) > 6):
+		r = common.SimpleResponse(400, "The tag of the clan must be between 1 and 6 characters long.")
+	case d.Description != nil && utf8.RuneCountInString(*d.Description) > 1024:
+		r = common.SimpleResponse(400, "The description of the clan can't be longer than 1024 characters.")
+	default:
+		return nil
+	}
+	return &r
+}
+
+// taken checks that no other clan than clan already uses the name or the tag.
+// As another clan could take them right after the check, the unique keys on
+// clans are what really enforce this, and their errors are turned into the
+// same responses by clanTaken.
+func (d clanData) taken(md common.MethodData, clan int) (*common.CodeMessager, error) {
+	var r common.CodeMessager
+	if d.Name != nil {
+		var exists bool
+		err := md.DB.QueryRow("SELECT EXISTS(SELECT 1 FROM clans WHERE name = ? AND id != ?)", *d.Name, clan).Scan(&exists)
+		if err != nil {
+			return nil, err
+		}
+		if exists {
+			r = common.SimpleResponse(409, "A clan with that name already exists.")
+			return &r, nil
+		}
+	}
+	if d.Tag != nil {
+		var exists bool
+		err := md.DB.QueryRow("SELECT EXISTS(SELECT 1 FROM clans WHERE LOWER(tag) = LOWER(?) AND id != ?)", *d.Tag, clan).Scan(&exists)
+		if err != nil {
+			return nil, err
+		}
+		if exists {
+			r = common.SimpleResponse(409, "That tag is already used by another clan.")
+			return &r, nil
+		}
+	}
+	return nil, nil
+}
+
+// clanTaken returns the response for err if it means that the name or the tag
+// of the clan are already used.
+func clanTaken(err error) (common.CodeMessager, bool) {
+	switch key, _ := common.DuplicateKey(err); key {
+	case "name":
+		return common.SimpleResponse(409, "A clan with that name already exists."), true
+	case "tag":
+		return common.SimpleResponse(409, "That tag is already used by another clan."), true
+	}
+	return nil, false
+}
+
+type clanResponse struct {
+	common.ResponseBase
+	singleClan
+}
+
+func getClan(md common.MethodData, id int) common.CodeMessager {
+	var r clanResponse
+	err := md.DB.QueryRow("SELECT id, name, description, tag, icon FROM clans WHERE id = ? LIMIT 1", id).Scan(
+		&r.ID, &r.Name, &r.Description, &r.Tag, &r.Icon)
+	switch {
+	case err == sql.ErrNoRows:
+		return common.SimpleResponse(404, "That clan could not be found!")
+	case err != nil:
+		md.Err(err)
+		return Err500
+	}
+	r.Code = 200
+	return r
+}
+
+// ClanManageCreatePOST creates a new clan, owned by the current user.
+func ClanManageCreatePOST(md common.MethodData) common.CodeMessager {
+	var d clanData
+	if err := md.Unmarshal(&d); err != nil {
+		return ErrBadJSON
+	}
+	switch {
+	case d.Name == nil:
+		return ErrMissingField("name")
+	case d.Tag == nil:
+		return ErrMissingField("tag")
+	}
+	if d.Description == nil {
+		d.Description = new(string)
+	}
+	if d.Icon == nil {
+		d.Icon = new(string)
+	}
+	if r := d.sanitise(); r != nil {
+		return *r
+	}
+
+	clan, _, err := clanMembership(md)
+	if err != nil {
+		md.Err(err)
+		return Err500
+	}
+	if clan != 0 {
+		return common.SimpleResponse(409, "You are already in a clan.")
+	}
+	r, err := d.taken(md, 0)
+	if err != nil {
+		md.Err(err)
+		return Err500
+	}
+	if r != nil {
+		return *r
+	}
+
+	tx, err := md.DB.Begin()
+	if err != nil {
+		md.Err(err)
+		return Err500
+	}
+	res, err := tx.Exec("INSERT INTO clans(name, description, icon, tag) VALUES (?, ?, ?, ?)",
+		*d.Name, *d.Description, *d.Icon, *d.Tag)
+	if err != nil {
+		tx.Rollback()
+		if r, ok := clanTaken(err); ok {
+			return r
+		}
+		md.Err(err)
+		return Err500
+	}
+	id, _ := res.LastInsertId()
+	_, err = tx.Exec("INSERT INTO user_clans(user, clan, perms) VALUES (?, ?, ?)", md.ID(), id, clanPermsOwner)
+	if err == nil {
+		_, err = setClanInvite(tx, int(id))
+	}
+	if err != nil {
+		tx.Rollback()
+		if key, _ := common.DuplicateKey(err); key == "user" {
+			return common.SimpleResponse(409, "You are already in a clan.")
+		}
+		md.Err(err)
+		return Err500
+	}
+	if err = tx.Commit(); err != nil {
+		md.Err(err)
+		return Err500
+	}
+
+	if err := updateClanBancho(md.R, md.ID()); err != nil {
+		md.Err(err)
+	}
+	updateClanLeaderboard(md, int(id))
+	return getClan(md, int(id))
+}
+
+// ClanManageEditPOST changes the name, tag, description or icon of the clan
+// of the current user.
+func ClanManageEditPOST(md common.MethodData) common.CodeMessager {
+	var d clanData
+	if err := md.Unmarshal(&d); err != nil {
+		return ErrBadJSON
+	}
+	if r := d.sanitise(); r != nil {
+		return *r
+	}
+	clan, _, resp := clanManager(md, clanPermsOwner)
+	if resp != nil {
+		return resp
+	}
+	r, err := d.taken(md, clan)
+	if err != nil {
+		md.Err(err)
+		return Err500
+	}
+	if r != nil {
+		return *r
+	}
+
+	q := new(common.UpdateQuery).
+		Add("name", d.Name).
+		Add("tag", d.Tag).
+		Add("description", d.Description).
+		Add("icon", d.Icon)
+	if q.Fields() != "" {
+		_, err = md.DB.Exec("UPDATE clans SET "+q.Fields()+" WHERE id = ? LIMIT 1", append(q.Parameters, clan)...)
+		if r, ok := clanTaken(err); ok {
+			return r
+		}
+		if err != nil {
+			md.Err(err)
+			return Err500
+		}
+	}
+	if d.Tag != nil {
+		var members []int
+		err = md.DB.Select(&members, "SELECT user FROM user_clans WHERE clan = ?", clan)
+		if err != nil {
+			md.Err(err)
+		}
+		for _, m := range members {
+			if err := updateClanBancho(md.R, m); err != nil {
+				md.Err(err)
+				break
+			}
+		}
+	}
+	return getClan(md, clan)
+}
+
+type clanMemberData struct {
+	User  int  `json:"user"`
+	Perms *int `json:"perms"`
+}
+
+// ClanManageKickPOST removes a member from the clan of the current user.
+// Officers can only kick the members with a lower permission level than
+// theirs.
+func ClanManageKickPOST(md common.MethodData) common.CodeMessager {
+	var d clanMemberData
+	if err := md.Unmarshal(&d); err != nil {
+		return ErrBadJSON
+	}
+	if d.User == 0 {
+		return ErrMissingField("user")
+	}
+	clan, perms, resp := clanManager(md, clanPermsOfficer)
+	if resp != nil {
+		return resp
+	}
+	if d.User == md.ID() {
+		return common.SimpleResponse(406, "You can't kick yourself out of your clan.")
+	}
+	targetPerms, ok, err := clanMemberPerms(md, clan, d.User)
+	switch {
+	case err != nil:
+		md.Err(err)
+		return Err500
+	case !ok:
+		return common.SimpleResponse(404, "That user is not in your clan.")
+	case targetPerms >= perms:
+		return common.SimpleResponse(403, "You can't kick a member with the same or higher permissions than yours.")
+	}
+
+	_, err = md.DB.Exec("DELETE FROM user_clans WHERE user = ? AND clan = ?", d.User, clan)
+	if err != nil {
+		md.Err(err)
+		return Err500
+	}
+	if err := updateClanBancho(md.R, d.User); err != nil {
+		md.Err(err)
+	}
+	updateClanLeaderboard(md, clan)
+	return common.SimpleResponse(200, "The user has been kicked from the clan.")
+}
+
+// ClanManageTransferPOST makes another member the owner of the clan of the
+// current user, who becomes an officer.
+func ClanManageTransferPOST(md common.MethodData) common.CodeMessager {
+	var d clanMemberData
+	if err := md.Unmarshal(&d); err != nil {
+		return ErrBadJSON
+	}
+	if d.User == 0 {
+		return ErrMissingField("user")
+	}
+	clan, _, resp := clanManager(md, clanPermsOwner)
+	if resp != nil {
+		return resp
+	}
+	if d.User == md.ID() {
+		return common.SimpleResponse(406, "You already own the clan.")
+	}
+	_, ok, err := clanMemberPerms(md, clan, d.User)
+	switch {
+	case err != nil:
+		md.Err(err)
+		return Err500
+	case !ok:
+		return common.SimpleResponse(404, "That user is not in your clan.")
+	}
+
+	tx, err := md.DB.Begin()
+	if err != nil {
+		md.Err(err)
+		return Err500
+	}
+	_, err = tx.Exec("UPDATE user_clans SET perms = ? WHERE user = ? AND clan = ?", clanPermsOwner, d.User, clan)
+	if err == nil {
+		_, err = tx.Exec("UPDATE user_clans SET perms = ? WHERE user = ? AND clan = ?", clanPermsOfficer, md.ID(), clan)
+	}
+	if err != nil {
+		tx.Rollback()
+		md.Err(err)
+		return Err500
+	}
+	if err = tx.Commit(); err != nil {
+		md.Err(err)
+		return Err500
+	}
+	return common.SimpleResponse(200, "The clan has been transferred.")
+}
+
+// ClanManagePermsPOST changes the permission level of a member of the clan
+// of the current user. The owner can only be changed with a transfer.
+func ClanManagePermsPOST(md common.MethodData) common.CodeMessager {
+	var d clanMemberData
+	if err := md.Unmarshal(&d); err != nil {
+		return ErrBadJSON
+	}
+	switch {
+	case d.User == 0:
+		return ErrMissingField("user")
+	case d.Perms == nil:
+		return ErrMissingField("perms")
+	case *d.Perms < clanPermsMember || *d.Perms >= clanPermsOwner:
+		return common.SimpleResponse(400, "perms must be at least 1 and lower than 8. To change the owner of the clan, transfer it.")
+	}
+	clan, _, resp := clanManager(md, clanPermsOwner)
+	if resp != nil {
+		return resp
+	}
+	if d.User == md.ID() {
+		return common.SimpleResponse(406, "To stop being the owner of the clan, transfer it.")
+	}
+	_, ok, err := clanMemberPerms(md, clan, d.User)
+	switch {
+	case err != nil:
+		md.Err(err)
+		return Err500
+	case !ok:
+		return common.SimpleResponse(404, "That user is not in your clan.")
+	}
+
+	_, err = md.DB.Exec("UPDATE user_clans SET perms = ? WHERE user = ? AND clan = ?", *d.Perms, d.User, clan)
+	if err != nil {
+		md.Err(err)
+		return Err500
+	}
+	return common.SimpleResponse(200, "The permissions of the member have been changed.")
+}
+
+// ClanManageInvitePOST generates a new invite code for the clan of the
+// current user, so that the old one can't be used anymore.
+func ClanManageInvitePOST(md common.MethodData) common.CodeMessager {
+	clan, _, resp := clanManager(md, clanPermsOwner)
+	if resp != nil {
+		return resp
+	}
+	invite, err := setClanInvite(md.DB, clan)
+	if err != nil {
+		md.Err(err)
+		return Err500
+	}
+	r := imFoolish{Invite: invite}
+	r.Code = 200
+	return r
+}
@@ -0,0 +1,175 @@
+package v1
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/osu-datenshi/api/common"
+	"github.com/osu-datenshi/lib/getrank"
+	"gopkg.in/thehowl/go-osuapi.v1"
+)
+
+type clanScoreUser struct {
+	ID       int    `json:"id"`
+	Username string `json:"username"`
+}
+
+// clanScore is a score made by a member of a clan, with both the beatmap and
+// the member.
+type clanScore struct {
+	userScore
+	User clanScoreUser `json:"user"`
+}
+
+type clanScoresResponse struct {
+	common.ResponseBase
+	Scores     []clanScore `json:"scores"`
+	NextCursor string      `json:"next_cursor,omitempty"`
+}
+
+const clanScoreFields = `
+		SELECT
+			s.id, s.beatmap_md5, s.score,
+			s.max_combo, s.full_combo, s.mods,
+			s.300_count, s.100_count, s.50_count,
+			s.gekis_count, s.katus_count, s.misses_count,
+			s.time, s.play_mode, s.accuracy, s.pp,
+			s.completed,
+
+			b.beatmap_id, b.beatmapset_id, b.beatmap_md5,
+			b.song_name, b.ar, b.od, b.difficulty_std,
+			b.difficulty_taiko, b.difficulty_ctb, b.difficulty_mania,
+			b.max_combo, b.hit_length, b.ranked,
+			b.ranked_status_freezed, b.latest_update,
+
+			users.id, users.username
+		`
+
+const clanScoreJoins = `
+		INNER JOIN beatmaps as b ON b.beatmap_md5 = s.beatmap_md5
+		INNER JOIN users ON users.id = s.userid
+		INNER JOIN user_clans as uc ON uc.user = s.userid
+		`
+
+// clanScoresParams reads the clan and the mode of a request for the scores of
+// a clan.
+func clanScoresParams(md common.MethodData) (clan, mode int, resp common.CodeMessager) {
+	clan = common.Int(md.Query("id"))
+	if clan == 0 {
+		return 0, 0, ErrMissingField("id")
+	}
+	mode = common.Int(md.Query("mode"))
+	if mode < 0 || mode > 3 {
+		mode = 0
+	}
+	return clan, mode, nil
+}
+
+func clanScoresPuts(md common.MethodData, page common.Page, key func(clanScore) interface{},
+	query string, params ...interface{}) common.CodeMessager {
+	rows, err := md.DB.Query(query, params...)
+	if err != nil {
+		md.Err(err)
+		return Err500
+	}
+	defer rows.Close()
+	r := clanScoresResponse{Scores: []clanScore{}}
+	for rows.Next() {
+		var (
+			s clanScore
+			b = &s.Beatmap
+		)
+		err = rows.Scan(
+			&s.ID, &s.BeatmapMD5, &s.Score.Score,
+			&s.MaxCombo, &s.FullCombo, &s.Mods,
+			&s.Count300, &s.Count100, &s.Count50,
+			&s.CountGeki, &s.CountKatu, &s.CountMiss,
+			&s.Time, &s.PlayMode, &s.Accuracy, &s.PP,
+			&s.Completed,
+
+			&b.BeatmapID, &b.BeatmapsetID, &b.BeatmapMD5,
+			&b.SongName, &b.AR, &b.OD, &b.Diff2.STD,
+			&b.Diff2.Taiko, &b.Diff2.CTB, &b.Diff2.Mania,
+			&b.MaxCombo, &b.HitLength, &b.Ranked,
+			&b.RankedStatusFrozen, &b.LatestUpdate,
+
+			&s.User.ID, &s.User.Username,
+		)
+		if err != nil {
+			md.Err(err)
+			return Err500
+		}
+		b.Difficulty = b.Diff2.STD
+		s.Rank = strings.ToUpper(getrank.GetRank(
+			osuapi.Mode(s.PlayMode),
+			osuapi.Mods(s.Mods),
+			s.Accuracy,
+			s.Count300,
+			s.Count100,
+			s.Count50,
+			s.CountMiss,
+		))
+		r.Scores = append(r.Scores, s)
+	}
+	if err := rows.Err(); err != nil {
+		md.Err(err)
+		return Err500
+	}
+	if len(r.Scores) > 0 {
+		last := r.Scores[len(r.Scores)-1]
+		r.NextCursor = page.Next(len(r.Scores), key(last), last.ID)
+	}
+	r.Code = 200
+	return r
+}
+
+// ClanScoresBestGET retrieves the best scores made by the members of a clan,
+// sorted by pp.
+func ClanScoresBestGET(md common.MethodData) common.CodeMessager {
+	clan, mode, resp := clanScoresParams(md)
+	if resp != nil {
+		return resp
+	}
+	page, ok := common.KeysetPaginate(md, common.Keyset{Column: "s.pp", IDColumn: "s.id"},
+		"ORDER BY s.pp DESC, s.score DESC", 100)
+	if !ok {
+		return ErrCursor
+	}
+	return clanScoresPuts(md, page, func(s clanScore) interface{} { return s.PP },
+		fmt.Sprintf(clanScoreFields+"FROM scores_master as s"+clanScoreJoins+`
+		WHERE
+			uc.clan = ?
+			AND s.completed = '3'
+			AND s.play_mode = ?
+			AND %s
+			AND users.privileges & 1 = 1
+			AND %s
+		%s %s`, md.SpecialMode().ScoresFilter("s"), page.Where, page.OrderBy, page.Limit),
+		append([]interface{}{clan, mode}, page.Params...)...)
+}
+
+// ClanActivityGET retrieves the latest first places obtained by the members
+// of a clan.
+func ClanActivityGET(md common.MethodData) common.CodeMessager {
+	clan, mode, resp := clanScoresParams(md)
+	if resp != nil {
+		return resp
+	}
+	page, ok := common.KeysetPaginate(md, common.Keyset{Column: "s.time", IDColumn: "s.id"},
+		"ORDER BY s.time DESC, s.id DESC", 100)
+	if !ok {
+		return ErrCursor
+	}
+	return clanScoresPuts(md, page, func(s clanScore) interface{} { return time.Time(s.Time).Unix() },
+		fmt.Sprintf(clanScoreFields+`FROM scores_first as sf
+		INNER JOIN scores_master as s ON s.id = sf.scoreid`+clanScoreJoins+`
+		WHERE
+			uc.clan = ?
+			AND s.play_mode = ?
+			AND %s
+			AND users.privileges & 1 = 1
+			AND %s
+		%s %s`, md.SpecialMode().ScoresFilter("s"), page.Where, page.OrderBy, page.Limit),
+		append([]interface{}{clan, mode}, page.Params...)...)
+}
@@ -10,6 +10,7 @@ import (
 var (
 	Err500     = common.SimpleResponse(500, "Uh oh... Seems like Aoba did something bad to API... Please try again! If it's broken... Please tell me in the Discord!")
 	ErrBadJSON = common.SimpleResponse(400, "Your JSON for this request is invalid.")
+	ErrCursor  = common.SimpleResponse(400, "That cursor is invalid.")
 )
 
 // ErrMissingField generates a response to a request when some fields in the JSON are missing.
@@ -25,22 +25,8 @@ type leaderboardResponse struct {
 	Users []leaderboardUser `json:"users"`
 }
 
-const rxUserQuery = `
-		SELECT
-			users.id, users.username, users.register_datetime, users.privileges, users.latest_activity,
-
-			rx_stats.username_aka, users_stats.country,
-			users_stats.play_style, users_stats.favourite_mode,
-
-			rx_stats.ranked_score_%[1]s, rx_stats.total_score_%[1]s, rx_stats.playcount_%[1]s,
-			users_stats.replays_watched_%[1]s, users_stats.total_hits_%[1]s,
-			rx_stats.avg_accuracy_%[1]s, rx_stats.pp_%[1]s
-		FROM users
-		INNER JOIN rx_stats ON rx_stats.id = users.id
-		INNER JOIN users_stats ON users_stats.id = users.id
-		WHERE users.id IN (?)
-		`
-
+// lbUserQuery selects the users on a leaderboard, with their stats in the
+// special mode whose table is %[2]s.
 const lbUserQuery = `
 		SELECT
 			users.id, users.username, users.register_datetime, users.privileges, users.latest_activity,
@@ -48,10 +34,11 @@ const lbUserQuery = `
 			users_stats.username_aka, users_stats.country,
 			users_stats.play_style, users_stats.favourite_mode,
 
-			users_stats.ranked_score_%[1]s, users_stats.total_score_%[1]s, users_stats.playcount_%[1]s,
+			st.ranked_score_%[1]s, st.total_score_%[1]s, st.playcount_%[1]s,
 			users_stats.replays_watched_%[1]s, users_stats.total_hits_%[1]s,
-			users_stats.avg_accuracy_%[1]s, users_stats.pp_%[1]s
+			st.avg_accuracy_%[1]s, st.pp_%[1]s
 		FROM users
+		INNER JOIN %[2]s as st ON st.id = users.id
 		INNER JOIN users_stats ON users_stats.id = users.id
 		WHERE users.id IN (?)
 		`
@@ -67,10 +54,8 @@ func LeaderboardGET(md common.MethodData) common.CodeMessager {
 	}
 	l := common.InString(1, md.Query("l"), 500, 50)
 
-	key := "ripple:leaderboard:" + m
-	if common.Int(md.Query("rx")) != 0 {
-		key = "ripple:leaderboard_relax:" + m
-	}
+	sm := md.SpecialMode()
+	key := sm.LeaderboardKey() + m
 	if md.Query("country") != "" {
 		key += ":" + md.Query("country")
 	}
@@ -88,10 +73,14 @@ func LeaderboardGET(md common.MethodData) common.CodeMessager {
 		return resp
 	}
 
-	query := fmt.Sprintf(lbUserQuery+` ORDER BY users_stats.pp_%[1]s DESC, users_stats.ranked_score_%[1]s DESC`, m)
-	if common.Int(md.Query("rx")) != 0 {
-		query = fmt.Sprintf(rxUserQuery+` ORDER BY rx_stats.pp_%[1]s DESC, rx_stats.ranked_score_%[1]s DESC`, m)
+	modeID := 0
+	for i, name := range modesToReadable {
+		if name == m {
+			modeID = i
+		}
 	}
+
+	query := fmt.Sprintf(lbUserQuery+` ORDER BY st.pp_%[1]s DESC, st.ranked_score_%[1]s DESC`, m, sm.StatsTable)
 	query, params, _ := sqlx.In(query, results)
 	rows, err := md.DB.Query(query, params...)
 	if err != nil {
@@ -114,40 +103,37 @@ func LeaderboardGET(md common.MethodData) common.CodeMessager {
 			continue
 		}
 		u.ChosenMode.Level = ocl.GetLevelPrecise(int64(u.ChosenMode.TotalScore))
-		if common.Int(md.Query("rx")) != 0 {
-			if i := relaxboardPosition(md.R, m, u.ID); i != nil {
-				u.ChosenMode.GlobalLeaderboardRank = i
-			}
-			if i := rxcountryPosition(md.R, m, u.ID, u.Country); i != nil {
-				u.ChosenMode.CountryLeaderboardRank = i
-			}
-		} else {
-			if i := leaderboardPosition(md.R, m, u.ID); i != nil {
-				u.ChosenMode.GlobalLeaderboardRank = i
-			}
-			if i := countryPosition(md.R, m, u.ID, u.Country); i != nil {
-				u.ChosenMode.CountryLeaderboardRank = i
-			}
+		if i := leaderboardPosition(md.R, sm, m, u.ID); i != nil {
+			u.ChosenMode.GlobalLeaderboardRank = i
+		}
+		if i := countryPosition(md.R, sm, m, u.ID, u.Country); i != nil {
+			u.ChosenMode.CountryLeaderboardRank = i
 		}
 		resp.Users = append(resp.Users, u)
 	}
-	return resp
-}
-
-func leaderboardPosition(r *redis.Client, mode string, user int) *int {
-	return _position(r, "ripple:leaderboard:"+mode, user)
-}
 
-func countryPosition(r *redis.Client, mode string, user int, country string) *int {
-	return _position(r, "ripple:leaderboard:"+mode+":"+strings.ToLower(country), user)
+	// The grades of the users on big pages may still be being counted, in
+	// which case they are left out of the response.
+	ids := make([]int, len(resp.Users))
+	for i, u := range resp.Users {
+		ids[i] = u.ID
+	}
+	grades, err := cachedUserGrades(md.DB, md.R, ids, modeID, sm)
+	if err != nil {
+		md.Err(err)
+	}
+	for i := range resp.Users {
+		resp.Users[i].ChosenMode.Grades = grades[resp.Users[i].ID]
+	}
+	return resp
 }
 
-func relaxboardPosition(r *redis.Client, mode string, user int) *int {
-	return _position(r, "ripple:leaderboard_relax:"+mode, user)
+func leaderboardPosition(r *redis.Client, sm common.SpecialMode, mode string, user int) *int {
+	return _position(r, sm.LeaderboardKey()+mode, user)
 }
 
-func rxcountryPosition(r *redis.Client, mode string, user int, country string) *int {
-	return _position(r, "ripple:leaderboard_relax:"+mode+":"+strings.ToLower(country), user)
+func countryPosition(r *redis.Client, sm common.SpecialMode, mode string, user int, country string) *int {
+	return _position(r, sm.LeaderboardKey()+mode+":"+strings.ToLower(country), user)
 }
 
 func _position(r *redis.Client, key string, user int) *int {
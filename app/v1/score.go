@@ -6,6 +6,7 @@ import (
 	"fmt"
 	"strconv"
 	"strings"
+	"time"
 
 	"gopkg.in/thehowl/go-osuapi.v1"
 	"github.com/osu-datenshi/api/common"
@@ -44,10 +45,15 @@ type beatmapScore struct {
 
 type scoresResponse struct {
 	common.ResponseBase
-	Scores []beatmapScore `json:"scores"`
+	Scores     []beatmapScore `json:"scores"`
+	NextCursor string         `json:"next_cursor,omitempty"`
 }
 
 // ScoresGET retrieves the top scores for a certain beatmap.
+// Only the scores of the special mode requested are returned.
+// Like the in-game leaderboard tabs, the scores can be filtered by mods (with
+// mods_exact, only the scores with exactly those mods), country and friends,
+// and best_per_user collapses them to the top play of each player.
 func ScoresGET(md common.MethodData) common.CodeMessager {
 	var (
 		where = new(common.WhereClause)
@@ -72,18 +78,55 @@ func ScoresGET(md common.MethodData) common.CodeMessager {
 	}
 	where.In("s.id", pm("id")...)
 
-	sort := common.Sort(md, common.SortConfiguration{
+	sortConfig := common.SortConfiguration{
 		Default: "s.pp DESC, s.score DESC",
 		Table:   "s",
 		Allowed: []string{"pp", "score", "accuracy", "id"},
-	})
+	}
+	sort := common.Sort(md, sortConfig)
 	if where.Clause == "" {
 		return ErrMissingField("must specify at least one queried item")
 	}
+	ks := common.SortKeyset(md, sortConfig, common.Keyset{Column: "s.pp", IDColumn: "s.id"})
+	page, ok := common.KeysetPaginate(md, ks, sort, 100)
+	if !ok {
+		return ErrCursor
+	}
+
+	where.Raw(md.SpecialMode().ScoresFilter("s"))
+	where.Where("us.country = ?", strings.ToUpper(md.Query("country")))
+	if common.Int(md.Query("friends")) > 0 {
+		// The friends of an user are only shown to tokens that can read
+		// confidential data, as in /friends.
+		switch {
+		case md.ID() == 0:
+			return common.SimpleResponse(401, "You need to be logged in to see the scores of your friends.")
+		case md.User.TokenPrivileges&common.PrivilegeReadConfidential == 0:
+			return common.SimpleResponse(403, "You don't have the privilege(s): "+common.Privileges(common.PrivilegeReadConfidential).String()+".")
+		}
+		where.Raw("(s.userid = ? OR s.userid IN (SELECT user2 FROM users_relationships WHERE user1 = ?))",
+			md.ID(), md.ID())
+	}
+
+	mods, modsParams := scoresModsClause(md, "s")
+	if common.Int(md.Query("best_per_user")) > 0 {
+		// Like the in-game leaderboards, consider every passed score, and only
+		// keep the top one of each player.
+		otherMods, otherModsParams := scoresModsClause(md, "s2")
+		col := strings.TrimPrefix(ks.Column, "s.")
+		where.Raw(`s.completed IN ('2', '3') AND `+mods+` AND NOT EXISTS (
+	SELECT 1 FROM scores_master as s2
+	WHERE s2.userid = s.userid AND s2.beatmap_md5 = s.beatmap_md5
+		AND s2.play_mode = s.play_mode AND s2.special_mode = s.special_mode
+		AND s2.completed IN ('2', '3') AND `+otherMods+`
+		AND (s2.`+col+` > s.`+col+` OR (s2.`+col+` = s.`+col+` AND s2.id < s.id))
+)`, append(modsParams, otherModsParams...)...)
+	} else {
+		where.Raw(`s.completed = '3' AND `+mods, modsParams...)
+	}
 
-	where.Where(` s.completed = '3' AND `+md.User.OnlyUserPublic(false)+` `+
-		genModeClause(md)+` `+sort+common.Paginate(md.Query("p"), md.Query("l"), 100), "FIF")
-	where.Params = where.Params[:len(where.Params)-1]
+	where.Raw(md.User.OnlyUserPublic(false)+` `+
+		genModeClause(md)+` AND `+page.Where+` `+page.OrderBy+page.Limit, page.Params...)
 
 	rows, err := md.DB.Query(`
 SELECT
@@ -136,10 +179,44 @@ INNER JOIN users_stats as us ON us.id = s.userid
 		))
 		r.Scores = append(r.Scores, s)
 	}
+	if len(r.Scores) > 0 {
+		last := r.Scores[len(r.Scores)-1]
+		r.NextCursor = page.Next(len(r.Scores), scoreSortKey(last.Score, ks.Column), last.ID)
+	}
 	r.Code = 200
 	return r
 }
 
+// scoresModsClause returns the condition filtering the scores of table by the
+// mods query parameter. If mods_exact is set, the scores must have exactly
+// those mods, otherwise they must have at least those mods.
+func scoresModsClause(md common.MethodData, table string) (string, []interface{}) {
+	if !md.HasQuery("mods") {
+		return "1", nil
+	}
+	mods := common.Int(md.Query("mods"))
+	if common.Int(md.Query("mods_exact")) > 0 {
+		return table + ".mods = ?", []interface{}{mods}
+	}
+	return "(" + table + ".mods & ?) = ?", []interface{}{mods, mods}
+}
+
+// scoreSortKey returns the value of the column a listing of scores is sorted
+// by.
+func scoreSortKey(s Score, column string) interface{} {
+	switch column {
+	case "s.score":
+		return s.Score
+	case "s.accuracy":
+		return s.Accuracy
+	case "s.id":
+		return s.ID
+	case "s.time":
+		return time.Time(s.Time).Unix()
+	}
+	return s.PP
+}
+
 type scoreReportData struct {
 	ScoreID   int             `json:"score_id"`
 	Data      json.RawMessage `json:"data"`
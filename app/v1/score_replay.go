@@ -0,0 +1,180 @@
+package v1
+
+import (
+	"database/sql"
+	"encoding/base64"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/osu-datenshi/api/common"
+	"github.com/osu-datenshi/api/osr"
+	"github.com/osu-datenshi/lib/getrank"
+	"gopkg.in/thehowl/go-osuapi.v1"
+)
+
+type scoreReplayResponse struct {
+	common.ResponseBase
+	Content  string `json:"content"`
+	Encoding string `json:"encoding"`
+}
+
+// ScoreReplayGET retrieves the replay data of a score, encoded in base64 like
+// in the get_replay endpoint of the osu! API.
+func ScoreReplayGET(md common.MethodData) common.CodeMessager {
+	id := common.Int(md.Query("id"))
+	if id == 0 {
+		return ErrMissingField("id")
+	}
+
+	var userID, mode, smode int
+	err := md.DB.QueryRow(`SELECT s.userid, s.play_mode, s.special_mode
+		FROM scores_master as s
+		INNER JOIN users ON users.id = s.userid
+		WHERE s.id = ? AND `+md.User.OnlyUserPublic(true)+`
+		LIMIT 1`, id).Scan(&userID, &mode, &smode)
+	switch {
+	case err == sql.ErrNoRows:
+		return common.SimpleResponse(404, "That score could not be found!")
+	case err != nil:
+		md.Err(err)
+		return Err500
+	}
+
+	data, cm := readReplay(md, id, userID, mode, smode)
+	if cm != nil {
+		return cm
+	}
+
+	r := scoreReplayResponse{
+		Content:  base64.StdEncoding.EncodeToString(data),
+		Encoding: "base64",
+	}
+	r.Code = 200
+	return r
+}
+
+// ScoreReplayFullGET builds the complete .osr file of a score, which can be
+// opened directly in osu!.
+func ScoreReplayFullGET(md common.MethodData) common.CodeMessager {
+	id := common.Int(md.Query("id"))
+	if id == 0 {
+		return ErrMissingField("id")
+	}
+
+	var (
+		s        Score
+		smode    int
+		userID   int
+		username string
+		songName sql.NullString
+	)
+	err := md.DB.QueryRow(`SELECT
+			s.id, s.beatmap_md5, s.score,
+			s.max_combo, s.full_combo, s.mods,
+			s.300_count, s.100_count, s.50_count,
+			s.gekis_count, s.katus_count, s.misses_count,
+			s.time, s.play_mode, s.special_mode, s.accuracy,
+			users.id, users.username, b.song_name
+		FROM scores_master as s
+		INNER JOIN users ON users.id = s.userid
+		LEFT JOIN beatmaps as b ON b.beatmap_md5 = s.beatmap_md5
+		WHERE s.id = ? AND `+md.User.OnlyUserPublic(true)+`
+		LIMIT 1`, id).Scan(
+		&s.ID, &s.BeatmapMD5, &s.Score,
+		&s.MaxCombo, &s.FullCombo, &s.Mods,
+		&s.Count300, &s.Count100, &s.Count50,
+		&s.CountGeki, &s.CountKatu, &s.CountMiss,
+		&s.Time, &s.PlayMode, &smode, &s.Accuracy,
+		&userID, &username, &songName,
+	)
+	switch {
+	case err == sql.ErrNoRows:
+		return common.SimpleResponse(404, "That score could not be found!")
+	case err != nil:
+		md.Err(err)
+		return Err500
+	}
+
+	data, cm := readReplay(md, id, userID, s.PlayMode, smode)
+	if cm != nil {
+		return cm
+	}
+
+	replay := osr.Replay{
+		Mode:       byte(s.PlayMode),
+		Version:    osr.Version,
+		BeatmapMD5: s.BeatmapMD5,
+		Username:   username,
+		Count300:   uint16(s.Count300),
+		Count100:   uint16(s.Count100),
+		Count50:    uint16(s.Count50),
+		CountGeki:  uint16(s.CountGeki),
+		CountKatu:  uint16(s.CountKatu),
+		CountMiss:  uint16(s.CountMiss),
+		Score:      int32(s.Score),
+		MaxCombo:   uint16(s.MaxCombo),
+		Perfect:    s.FullCombo,
+		Mods:       int32(s.Mods),
+		Time:       time.Time(s.Time),
+		Data:       data,
+		ScoreID:    int64(s.ID),
+	}
+	replay.ReplayMD5 = replay.Hash(strings.ToUpper(getrank.GetRank(
+		osuapi.Mode(s.PlayMode),
+		osuapi.Mods(s.Mods),
+		s.Accuracy,
+		s.Count300,
+		s.Count100,
+		s.Count50,
+		s.CountMiss,
+	)))
+	body, err := replay.MarshalBinary()
+	if err != nil {
+		md.Err(err)
+		return Err500
+	}
+
+	r := common.RawResponse{
+		ContentType: "application/octet-stream",
+		Filename: replayFilename.Replace(fmt.Sprintf("%s - %s (%s).osr",
+			username, songName.String, time.Time(s.Time).Format("2006-01-02"))),
+		Body: body,
+	}
+	r.Code = 200
+	return r
+}
+
+// replayFilename removes from the name of a replay file the characters that
+// can't be in a file name, or in the Content-Disposition header.
+var replayFilename = strings.NewReplacer(
+	`"`, "", `\`, "", "/", "", ":", "", "*", "", "?", "", "<", "", ">", "", "|", "",
+	"\r", "", "\n", "",
+)
+
+// readReplay reads the replay data of a score, and counts it as watched if
+// the score was not made by the user requesting it, and it was not already
+// downloaded from the same IP address recently.
+func readReplay(md common.MethodData, id, userID, mode, smode int) ([]byte, common.CodeMessager) {
+	data, err := ioutil.ReadFile(common.ReplayPath(id))
+	switch {
+	case os.IsNotExist(err):
+		return nil, common.SimpleResponse(404, "The replay of that score is not available.")
+	case err != nil:
+		md.Err(err)
+		return nil, Err500
+	}
+
+	if md.ID() != userID {
+		first, err := common.NewReplayView(md.R, id, md.ClientIP())
+		if err == nil && first {
+			err = common.ReplayWatched(md.DB, userID, mode, smode)
+		}
+		if err != nil {
+			md.Err(err)
+		}
+	}
+	return data, nil
+}
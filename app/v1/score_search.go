@@ -0,0 +1,283 @@
+package v1
+
+import (
+	"database/sql"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/osu-datenshi/api/common"
+	"github.com/osu-datenshi/lib/getrank"
+	"gopkg.in/thehowl/go-osuapi.v1"
+)
+
+const (
+	// scoreSearchScanLimit is the maximum number of scores read from the
+	// database for a page of a search, when they have to be filtered by
+	// rank after being read.
+	scoreSearchScanLimit = 1000
+	// scoreSearchMaxTime is the maximum time, in milliseconds, a search can
+	// take in MySQL.
+	scoreSearchMaxTime = 5000
+)
+
+type searchScore struct {
+	Score
+	Beatmap beatmap  `json:"beatmap"`
+	User    userData `json:"user"`
+}
+
+type scoreSearchResponse struct {
+	common.ResponseBase
+	Scores     []searchScore `json:"scores"`
+	NextCursor string        `json:"next_cursor,omitempty"`
+}
+
+// starsColumn is the difficulty of the beatmap in the mode of the score.
+const starsColumn = `CASE s.play_mode
+	WHEN 1 THEN b.difficulty_taiko
+	WHEN 2 THEN b.difficulty_ctb
+	WHEN 3 THEN b.difficulty_mania
+	ELSE b.difficulty_std END`
+
+// ScoresSearchGET searches through the best scores of all the users.
+// Scores can be filtered by:
+//   - mods (mods the scores must have) and nomods (mods they must not have)
+//   - min_pp, max_pp, min_acc, max_acc, min_stars, max_stars
+//   - rank (e.g. S or SHD, can be repeated)
+//   - from and to, either unix timestamps or dates (2006-01-02)
+//   - fc (0 or 1), mode, smode (or rx), ranked (ranked status of the beatmap)
+//   - user (an user ID) and country
+//
+// Results are paginated with cursors only. Searches that are estimated to be
+// too expensive are refused.
+func ScoresSearchGET(md common.MethodData) common.CodeMessager {
+	where := new(common.WhereClause).
+		Where("s.play_mode = ?", md.Query("mode"), "0", "1", "2", "3").
+		Where("s.full_combo = ?", md.Query("fc"), "0", "1").
+		Where("us.country = ?", strings.ToUpper(md.Query("country")))
+	if u := common.Int(md.Query("user")); u != 0 {
+		where.Raw("s.userid = ?", u)
+	}
+	where.Raw("s.completed = '3' AND " + md.SpecialMode().ScoresFilter("s")).
+		Raw(md.User.OnlyUserPublic(false))
+	if md.HasQuery("ranked") {
+		where.Raw("b.ranked = ?", common.Int(md.Query("ranked")))
+	}
+	if m := common.Int(md.Query("mods")); m > 0 {
+		where.Raw("(s.mods & ?) = ?", m, m)
+	}
+	if m := common.Int(md.Query("nomods")); m > 0 {
+		where.Raw("(s.mods & ?) = 0", m)
+	}
+	for _, r := range [...]struct {
+		param, clause string
+	}{
+		{"min_pp", "s.pp >= ?"},
+		{"max_pp", "s.pp <= ?"},
+		{"min_acc", "s.accuracy >= ?"},
+		{"max_acc", "s.accuracy <= ?"},
+		{"min_stars", "(" + starsColumn + ") >= ?"},
+		{"max_stars", "(" + starsColumn + ") <= ?"},
+	} {
+		if v, err := strconv.ParseFloat(md.Query(r.param), 64); err == nil {
+			where.Raw(r.clause, v)
+		}
+	}
+	if from, ok := parseSearchTime(md.Query("from")); ok {
+		where.Raw("s.time >= ?", from)
+	}
+	if to, ok := parseSearchTime(md.Query("to")); ok {
+		where.Raw("s.time <= ?", to)
+	}
+	ranks := make(map[string]bool)
+	for _, r := range md.Ctx.QueryArgs().PeekMulti("rank") {
+		ranks[strings.ToUpper(string(r))] = true
+	}
+
+	ks := common.SortKeyset(md, common.SortConfiguration{
+		Table:   "s",
+		Allowed: []string{"pp", "score", "accuracy", "time", "id"},
+	}, common.Keyset{Column: "s.pp", IDColumn: "s.id"})
+	seek, seekParams, ok := ks.Seek(md.Query("cursor"))
+	if !ok {
+		return ErrCursor
+	}
+	size := common.PageLimit(md.Query("l"), 100)
+	scan := size
+	if len(ranks) > 0 {
+		scan = scoreSearchScanLimit
+	}
+	where.Raw(seek, seekParams...)
+
+	query := fmt.Sprintf(`SELECT /*+ MAX_EXECUTION_TIME(%d) */
+	s.id, s.beatmap_md5, s.score,
+	s.max_combo, s.full_combo, s.mods,
+	s.300_count, s.100_count, s.50_count,
+	s.gekis_count, s.katus_count, s.misses_count,
+	s.time, s.play_mode, s.accuracy, s.pp,
+	s.completed,
+
+	b.beatmap_id, b.beatmapset_id, b.beatmap_md5,
+	b.song_name, b.ar, b.od, b.difficulty_std,
+	b.difficulty_taiko, b.difficulty_ctb, b.difficulty_mania,
+	b.max_combo, b.hit_length, b.ranked,
+	b.ranked_status_freezed, b.latest_update,
+
+	users.id, users.username, users.register_datetime, users.privileges,
+	users.latest_activity, us.username_aka, us.country
+FROM scores_master as s
+INNER JOIN beatmaps as b ON b.beatmap_md5 = s.beatmap_md5
+INNER JOIN users ON users.id = s.userid
+INNER JOIN users_stats as us ON us.id = s.userid
+%s %s LIMIT %d`, scoreSearchMaxTime, where.Clause, ks.OrderBy(), scan)
+
+	cost, err := estimatedRows(md.DB, query, where.Params...)
+	if err != nil {
+		md.Err(err)
+		return Err500
+	}
+	if max := scoreSearchMaxRows(); cost > max {
+		return common.SimpleResponse(400, "This search is too broad, try narrowing it down with more filters.")
+	}
+
+	rows, err := md.DB.Query(query, where.Params...)
+	if err != nil {
+		md.Err(err)
+		return Err500
+	}
+	defer rows.Close()
+	r := scoreSearchResponse{Scores: []searchScore{}}
+	var (
+		last    Score
+		scanned int
+	)
+	for len(r.Scores) < size && rows.Next() {
+		var (
+			s searchScore
+			b = &s.Beatmap
+			u = &s.User
+		)
+		err := rows.Scan(
+			&s.ID, &s.BeatmapMD5, &s.Score.Score,
+			&s.MaxCombo, &s.FullCombo, &s.Mods,
+			&s.Count300, &s.Count100, &s.Count50,
+			&s.CountGeki, &s.CountKatu, &s.CountMiss,
+			&s.Time, &s.PlayMode, &s.Accuracy, &s.PP,
+			&s.Completed,
+
+			&b.BeatmapID, &b.BeatmapsetID, &b.BeatmapMD5,
+			&b.SongName, &b.AR, &b.OD, &b.Diff2.STD,
+			&b.Diff2.Taiko, &b.Diff2.CTB, &b.Diff2.Mania,
+			&b.MaxCombo, &b.HitLength, &b.Ranked,
+			&b.RankedStatusFrozen, &b.LatestUpdate,
+
+			&u.ID, &u.Username, &u.RegisteredOn, &u.Privileges,
+			&u.LatestActivity, &u.UsernameAKA, &u.Country,
+		)
+		if err != nil {
+			md.Err(err)
+			return Err500
+		}
+		scanned++
+		last = s.Score
+		b.Difficulty = b.Diff2.STD
+		s.Rank = strings.ToUpper(getrank.GetRank(
+			osuapi.Mode(s.PlayMode),
+			osuapi.Mods(s.Mods),
+			s.Accuracy,
+			s.Count300,
+			s.Count100,
+			s.Count50,
+			s.CountMiss,
+		))
+		if len(ranks) > 0 && !ranks[s.Rank] {
+			continue
+		}
+		r.Scores = append(r.Scores, s)
+	}
+	if err := rows.Err(); err != nil {
+		md.Err(err)
+		return Err500
+	}
+
+	// There may be more results if the page is full, or if we stopped
+	// before finding enough scores with the requested ranks.
+	if len(r.Scores) == size || scanned == scan {
+		r.NextCursor = common.EncodeCursor(scoreSortKey(last, ks.Column), last.ID)
+	}
+	r.Code = 200
+	return r
+}
+
+// parseSearchTime parses either an unix timestamp or a date.
+func parseSearchTime(s string) (int64, bool) {
+	if s == "" {
+		return 0, false
+	}
+	if i, err := strconv.ParseInt(s, 10, 64); err == nil {
+		return i, true
+	}
+	t, err := time.Parse("2006-01-02", s)
+	if err != nil {
+		return 0, false
+	}
+	return t.Unix(), true
+}
+
+// scoreSearchMaxRows returns the maximum number of rows a score search may
+// examine.
+func scoreSearchMaxRows() int64 {
+	if c := common.GetConf(); c != nil && c.ScoreSearchMaxRows > 0 {
+		return int64(c.ScoreSearchMaxRows)
+	}
+	return 1000000
+}
+
+// estimatedRows asks MySQL how many rows it expects to examine to run a
+// query, multiplying the rows it expects to read from each table. If the
+// database doesn't say, 0 is returned.
+func estimatedRows(db *sqlx.DB, query string, params ...interface{}) (int64, error) {
+	rows, err := db.Query("EXPLAIN "+query, params...)
+	if err != nil {
+		return 0, err
+	}
+	defer rows.Close()
+	cols, err := rows.Columns()
+	if err != nil {
+		return 0, err
+	}
+	idx := -1
+	for i, c := range cols {
+		if strings.EqualFold(c, "rows") {
+			idx = i
+		}
+	}
+	if idx == -1 {
+		return 0, nil
+	}
+
+	total := int64(1)
+	vals := make([]sql.RawBytes, len(cols))
+	dest := make([]interface{}, len(cols))
+	for i := range vals {
+		dest[i] = &vals[i]
+	}
+	for rows.Next() {
+		if err := rows.Scan(dest...); err != nil {
+			return 0, err
+		}
+		n, err := strconv.ParseInt(string(vals[idx]), 10, 64)
+		if err != nil || n < 1 {
+			continue
+		}
+		// don't overflow
+		if total > (1<<62)/n {
+			return 1 << 62, nil
+		}
+		total *= n
+	}
+	return total, rows.Err()
+}
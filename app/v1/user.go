@@ -5,6 +5,7 @@ import (
 	"database/sql"
 	"strconv"
 	"strings"
+	"time"
 	"unicode"
 
 	"github.com/jmoiron/sqlx"
@@ -66,7 +67,8 @@ func userPutsSingle(md common.MethodData, row *sqlx.Row) common.CodeMessager {
 
 type userPutsMultiUserData struct {
 	common.ResponseBase
-	Users []userData `json:"users"`
+	Users      []userData `json:"users"`
+	NextCursor string     `json:"next_cursor,omitempty"`
 }
 
 func userPutsMulti(md common.MethodData) common.CodeMessager {
@@ -91,8 +93,7 @@ func userPutsMulti(md common.MethodData) common.CodeMessager {
 		extraJoin = " LEFT JOIN privileges_groups as pg ON users.privileges & pg.privileges = pg.privileges "
 	}
 
-	query := userFields + extraJoin + wh.ClauseSafe() + " AND " + md.User.OnlyUserPublic(true) +
-		" " + common.Sort(md, common.SortConfiguration{
+	sortConfig := common.SortConfiguration{
 		Allowed: []string{
 			"id",
 			"username",
@@ -103,11 +104,23 @@ func userPutsMulti(md common.MethodData) common.CodeMessager {
 		},
 		Default: "id ASC",
 		Table:   "users",
-	}) +
-		" " + common.Paginate(md.Query("p"), md.Query("l"), 100)
+	}
+	// When using cursors, only the fields we return can be sorted on, as the
+	// cursor must contain the value of the last user.
+	keysetConfig := sortConfig
+	keysetConfig.Allowed = []string{"id", "username", "privileges", "latest_activity"}
+	keysetConfig.DefaultSorting = "ASC"
+	ks := common.SortKeyset(md, keysetConfig, common.Keyset{IDColumn: "users.id", Ascending: true})
+	page, ok := common.KeysetPaginate(md, ks, common.Sort(md, sortConfig), 100)
+	if !ok {
+		return ErrCursor
+	}
+
+	query := userFields + extraJoin + wh.ClauseSafe() + " AND " + md.User.OnlyUserPublic(true) +
+		" AND " + page.Where + " " + page.OrderBy + " " + page.Limit
 
 	// query execution
-	rows, err := md.DB.Queryx(query, wh.Params...)
+	rows, err := md.DB.Queryx(query, append(wh.Params, page.Params...)...)
 	if err != nil {
 		md.Err(err)
 		return Err500
@@ -122,10 +135,28 @@ func userPutsMulti(md common.MethodData) common.CodeMessager {
 		}
 		r.Users = append(r.Users, u)
 	}
+	if len(r.Users) > 0 {
+		last := r.Users[len(r.Users)-1]
+		r.NextCursor = page.Next(len(r.Users), userSortKey(last, ks.Column), last.ID)
+	}
 	r.Code = 200
 	return r
 }
 
+// userSortKey returns the value of the column a listing of users is sorted
+// by.
+func userSortKey(u userData, column string) interface{} {
+	switch column {
+	case "users.username":
+		return u.Username
+	case "users.privileges":
+		return u.Privileges
+	case "users.latest_activity":
+		return time.Time(u.LatestActivity).Unix()
+	}
+	return u.ID
+}
+
 // UserSelfGET is a shortcut for /users/id/self. (/users/self)
 func UserSelfGET(md common.MethodData) common.CodeMessager {
 	md.Ctx.Request.URI().SetQueryString("id=self")
@@ -184,6 +215,8 @@ type modeData struct {
 	PP                     int     `json:"pp"`
 	GlobalLeaderboardRank  *int    `json:"global_leaderboard_rank"`
 	CountryLeaderboardRank *int    `json:"country_leaderboard_rank"`
+	Peak                   *peakData `json:"peak,omitempty"`
+	Grades                 *GradeCounts `json:"grades,omitempty"`
 }
 type userFullResponse struct {
 	common.ResponseBase
@@ -247,14 +280,35 @@ type userNotFullResponse struct {
 
 
 
-// RelaxUserFullGET gets all of... bluh.. I'm tired...
+// UserFullGET gets all of an user's information, with one exception: their userpage.
+// The stats are the ones of the special mode requested.
+func UserFullGET(md common.MethodData) common.CodeMessager {
+	return userFull(md, md.SpecialMode())
+}
+
+// RelaxUserFullGET is UserFullGET with the relax stats, whatever the special
+// mode requested.
 func RelaxUserFullGET(md common.MethodData) common.CodeMessager {
+	return userFull(md, common.Relax)
+}
+
+// AutopilotUserFullGET is UserFullGET with the autopilot stats, whatever the
+// special mode requested.
+func AutopilotUserFullGET(md common.MethodData) common.CodeMessager {
+	return userFull(md, common.Autopilot)
+}
+
+// userFull gets all of an user's information, with the stats of a special mode.
+func userFull(md common.MethodData, sm common.SpecialMode) common.CodeMessager {
 	shouldRet, whereClause, param := whereClauseUser(md, "users")
 	if shouldRet != nil {
 		return *shouldRet
 	}
 
 	// Hellest query I've ever done.
+	// The stats are IFNULL'd, as the users registered after a special mode
+	// was added don't have a row in its table until the score server makes
+	// one for them.
 	query := `
 SELECT
 	users.id, users.username, users.register_datetime, users.privileges, users.latest_activity,
@@ -264,32 +318,32 @@ SELECT
 	us.custom_badge_icon, us.custom_badge_name, us.can_custom_badge,
 	us.show_custom_badge,
 
-	rs.ranked_score_std, rs.total_score_std, rs.playcount_std,
+	IFNULL(st.ranked_score_std, 0), IFNULL(st.total_score_std, 0), IFNULL(st.playcount_std, 0),
 	us.replays_watched_std, us.total_hits_std,
-	rs.avg_accuracy_std, rs.pp_std, rs.playtime_std,
+	IFNULL(st.avg_accuracy_std, 0), IFNULL(st.pp_std, 0), IFNULL(st.playtime_std, 0),
 
-	rs.ranked_score_taiko, rs.total_score_taiko, rs.playcount_taiko,
+	IFNULL(st.ranked_score_taiko, 0), IFNULL(st.total_score_taiko, 0), IFNULL(st.playcount_taiko, 0),
 	us.replays_watched_taiko, us.total_hits_taiko,
-	rs.avg_accuracy_taiko, rs.pp_taiko, rs.playtime_taiko,
+	IFNULL(st.avg_accuracy_taiko, 0), IFNULL(st.pp_taiko, 0), IFNULL(st.playtime_taiko, 0),
 
-	rs.ranked_score_ctb, rs.total_score_ctb, rs.playcount_ctb,
+	IFNULL(st.ranked_score_ctb, 0), IFNULL(st.total_score_ctb, 0), IFNULL(st.playcount_ctb, 0),
 	us.replays_watched_ctb, us.total_hits_ctb,
-	rs.avg_accuracy_ctb, rs.pp_ctb, rs.playtime_ctb,
+	IFNULL(st.avg_accuracy_ctb, 0), IFNULL(st.pp_ctb, 0), IFNULL(st.playtime_ctb, 0),
 
-	rs.ranked_score_mania, rs.total_score_mania, rs.playcount_mania,
+	IFNULL(st.ranked_score_mania, 0), IFNULL(st.total_score_mania, 0), IFNULL(st.playcount_mania, 0),
 	us.replays_watched_mania, us.total_hits_mania,
-	rs.avg_accuracy_mania, rs.pp_mania, rs.playtime_mania,
+	IFNULL(st.avg_accuracy_mania, 0), IFNULL(st.pp_mania, 0), IFNULL(st.playtime_mania, 0),
 
 	users.silence_reason, users.silence_end,
 	users.notes, users.ban_datetime, users.email
 
 FROM users
 LEFT JOIN users_stats as us ON users.id = us.id
-LEFT JOIN rx_stats as rs ON users.id = rs.id
+LEFT JOIN ` + sm.StatsTable + ` as st ON users.id = st.id
 WHERE ` + whereClause + ` AND ` + md.User.OnlyUserPublic(true) + `
 LIMIT 1
 `
-	// Whatever man.
+	// Fuck.
 	r := userFullResponse{}
 	var (
 		b    singleBadge
@@ -339,155 +393,23 @@ LIMIT 1
 	for modeID, m := range [...]*modeData{&r.STD, &r.Taiko, &r.CTB, &r.Mania} {
 		m.Level = ocl.GetLevelPrecise(int64(m.TotalScore))
 
-		if i := relaxboardPosition(md.R, modesToReadable[modeID], r.ID); i != nil {
+		if i := leaderboardPosition(md.R, sm, modesToReadable[modeID], r.ID); i != nil {
 			m.GlobalLeaderboardRank = i
 		}
-		if i := rxcountryPosition(md.R, modesToReadable[modeID], r.ID, r.Country); i != nil {
+		if i := countryPosition(md.R, sm, modesToReadable[modeID], r.ID, r.Country); i != nil {
 			m.CountryLeaderboardRank = i
 		}
 	}
 
-	rows, err := md.DB.Query("SELECT b.id, b.name, b.icon FROM user_badges ub "+
-		"LEFT JOIN badges b ON ub.badge = b.id WHERE user = ?", r.ID)
+	err = setUserPeaks(md.DB, r.ID, sm, [...]*modeData{&r.STD, &r.Taiko, &r.CTB, &r.Mania})
 	if err != nil {
 		md.Err(err)
 	}
-
-	for rows.Next() {
-		var badge singleBadge
-		err := rows.Scan(&badge.ID, &badge.Name, &badge.Icon)
-		if err != nil {
-			md.Err(err)
-			continue
-		}
-		r.Badges = append(r.Badges, badge)
-	}
-
-	if md.User.TokenPrivileges&common.PrivilegeManageUser == 0 {
-		r.CMNotes = nil
-		r.BanDate = nil
-		r.Email = ""
-	}
-
-	rows, err = md.DB.Query("SELECT c.id, c.name, c.description, c.tag, c.icon FROM user_clans uc "+
-		"LEFT JOIN clans c ON uc.clan = c.id WHERE user = ?", r.ID)
+	err = setUserGrades(md.DB, md.R, r.ID, sm, [...]*modeData{&r.STD, &r.Taiko, &r.CTB, &r.Mania})
 	if err != nil {
 		md.Err(err)
 	}
 
-	for rows.Next() {
-		var clan singleClan
-		err = rows.Scan(&clan.ID, &clan.Name, &clan.Description, &clan.Tag, &clan.Icon)
-		if err != nil {
-			md.Err(err)
-			continue
-		}
-		r.Clan = clan
-	}
-
-	r.Code = 200
-	return r
-}
-
-// UserFullGET gets all of an user's information, with one exception: their userpage.
-func UserFullGET(md common.MethodData) common.CodeMessager {
-	shouldRet, whereClause, param := whereClauseUser(md, "users")
-	if shouldRet != nil {
-		return *shouldRet
-	}
-
-	// Hellest query I've ever done.
-	query := `
-SELECT
-	users.id, users.username, users.register_datetime, users.privileges, users.latest_activity,
-
-	us.username_aka, us.country, us.play_style, us.favourite_mode,
-
-	us.custom_badge_icon, us.custom_badge_name, us.can_custom_badge,
-	us.show_custom_badge,
-
-	us.ranked_score_std, us.total_score_std, us.playcount_std,
-	us.replays_watched_std, us.total_hits_std,
-	us.avg_accuracy_std, us.pp_std, us.playtime_std,
-
-	us.ranked_score_taiko, us.total_score_taiko, us.playcount_taiko,
-	us.replays_watched_taiko, us.total_hits_taiko,
-	us.avg_accuracy_taiko, us.pp_taiko, us.playtime_taiko,
-
-	us.ranked_score_ctb, us.total_score_ctb, us.playcount_ctb,
-	us.replays_watched_ctb, us.total_hits_ctb,
-	us.avg_accuracy_ctb, us.pp_ctb, us.playtime_ctb,
-
-	us.ranked_score_mania, us.total_score_mania, us.playcount_mania,
-	us.replays_watched_mania, us.total_hits_mania,
-	us.avg_accuracy_mania, us.pp_mania, us.playtime_mania,
-
-	users.silence_reason, users.silence_end,
-	users.notes, users.ban_datetime, users.email
-
-FROM users
-LEFT JOIN users_stats as us ON users.id = us.id
-WHERE ` + whereClause + ` AND ` + md.User.OnlyUserPublic(true) + `
-LIMIT 1
-`
-	// Fuck.
-	r := userFullResponse{}
-	var (
-		b    singleBadge
-		can  bool
-		show bool
-	)
-	err := md.DB.QueryRow(query, param).Scan(
-		&r.ID, &r.Username, &r.RegisteredOn, &r.Privileges, &r.LatestActivity,
-
-		&r.UsernameAKA, &r.Country,
-		&r.PlayStyle, &r.FavouriteMode,
-
-		&b.Icon, &b.Name, &can, &show,
-
-		&r.STD.RankedScore, &r.STD.TotalScore, &r.STD.PlayCount,
-		&r.STD.ReplaysWatched, &r.STD.TotalHits,
-		&r.STD.Accuracy, &r.STD.PP, &r.STD.PlayTime,
-
-		&r.Taiko.RankedScore, &r.Taiko.TotalScore, &r.Taiko.PlayCount,
-		&r.Taiko.ReplaysWatched, &r.Taiko.TotalHits,
-		&r.Taiko.Accuracy, &r.Taiko.PP, &r.Taiko.PlayTime,
-
-		&r.CTB.RankedScore, &r.CTB.TotalScore, &r.CTB.PlayCount,
-		&r.CTB.ReplaysWatched, &r.CTB.TotalHits,
-		&r.CTB.Accuracy, &r.CTB.PP, &r.CTB.PlayTime,
-
-		&r.Mania.RankedScore, &r.Mania.TotalScore, &r.Mania.PlayCount,
-		&r.Mania.ReplaysWatched, &r.Mania.TotalHits,
-		&r.Mania.Accuracy, &r.Mania.PP, &r.Mania.PlayTime,
-
-		&r.SilenceInfo.Reason, &r.SilenceInfo.End,
-		&r.CMNotes, &r.BanDate, &r.Email,
-	)
-	switch {
-	case err == sql.ErrNoRows:
-		return common.SimpleResponse(404, "That user could not be found!")
-	case err != nil:
-		md.Err(err)
-		return Err500
-	}
-
-	can = can && show && common.UserPrivileges(r.Privileges)&common.UserPrivilegeDonor > 0
-	if can && (b.Name != "" || b.Icon != "") {
-		r.CustomBadge = &b
-	}
-
-	for modeID, m := range [...]*modeData{&r.STD, &r.Taiko, &r.CTB, &r.Mania} {
-		m.Level = ocl.GetLevelPrecise(int64(m.TotalScore))
-
-		if i := leaderboardPosition(md.R, modesToReadable[modeID], r.ID); i != nil {
-			m.GlobalLeaderboardRank = i
-		}
-		if i := countryPosition(md.R, modesToReadable[modeID], r.ID, r.Country); i != nil {
-			m.CountryLeaderboardRank = i
-		}
-	}
-
 	rows, err := md.DB.Query("SELECT b.id, b.name, b.icon FROM user_badges ub "+
 		"LEFT JOIN badges b ON ub.badge = b.id WHERE user = ?", r.ID)
 	if err != nil {
@@ -0,0 +1,269 @@
+package v1
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/osu-datenshi/api/common"
+	"github.com/osu-datenshi/lib/getrank"
+	"gopkg.in/redis.v5"
+	"gopkg.in/thehowl/go-osuapi.v1"
+)
+
+// gradesExpiration is how long the grade counts of an user are kept before
+// being counted again from the database, which catches up with the changes
+// that don't come with a score submission, such as wipes.
+const gradesExpiration = 7 * 24 * time.Hour
+
+// GradeCounts is how many best scores of an user have each grade in a mode.
+type GradeCounts struct {
+	XH int `json:"xh"`
+	X  int `json:"x"`
+	SH int `json:"sh"`
+	S  int `json:"s"`
+	A  int `json:"a"`
+}
+
+// gradesKey is the hash holding how many best scores of an user have each
+// grade, as returned by getrank.GetRank. The grade of each of the best scores
+// is kept in the hash gradesKey + ":beatmaps", by beatmap md5, so that the
+// counts can be updated when a best score is replaced.
+func gradesKey(mode int, sm common.SpecialMode, user int) string {
+	return "api:grades:" + strconv.Itoa(sm.ID) + ":" + modesToReadable[mode] + ":" + strconv.Itoa(user)
+}
+
+// updateGrades moves the best score of a beatmap to its new grade. It does
+// nothing and returns 0 if the grades of the user are not in redis.
+var updateGrades = redis.NewScript(`
+if redis.call("EXISTS", KEYS[1]) == 0 then
+	return 0
+end
+local old = redis.call("HGET", KEYS[2], ARGV[1])
+if old then
+	redis.call("HINCRBY", KEYS[1], old, -1)
+end
+redis.call("HSET", KEYS[2], ARGV[1], ARGV[2])
+redis.call("HINCRBY", KEYS[1], ARGV[2], 1)
+return 1
+`)
+
+// scoreGrade is getrank.GetRank on the columns of scores_master. The silver
+// grades are spelled "ssh" and "sh" by getrank in osu!standard and taiko, and
+// "sshd" and "shd" in the other modes: they are always returned as the latter.
+func scoreGrade(mode, mods int, acc float64, c300, c100, c50, misses int) string {
+	g := getrank.GetRank(osuapi.Mode(mode), osuapi.Mods(mods), acc, c300, c100, c50, misses)
+	switch g {
+	case "ssh":
+		return "sshd"
+	case "sh":
+		return "shd"
+	}
+	return g
+}
+
+// countUserGrades counts the grades of the best scores of an user in a mode
+// from the database, and stores them in redis.
+func countUserGrades(db *sqlx.DB, r *redis.Client, user, mode int, sm common.SpecialMode) (map[string]int, error) {
+	counts, err := countGrades(db, r, []int{user}, mode, sm)
+	return counts[user], err
+}
+
+// countGrades is countUserGrades for many users at once, returning the counts
+// of each of them.
+func countGrades(db *sqlx.DB, r *redis.Client, users []int, mode int, sm common.SpecialMode) (map[int]map[string]int, error) {
+	query, params, _ := sqlx.In(`SELECT
+			userid, beatmap_md5, mods, accuracy, 300_count, 100_count, 50_count, misses_count
+		FROM scores_master
+		WHERE userid IN (?) AND play_mode = ? AND special_mode = ? AND completed = '3'`,
+		users, mode, sm.ID)
+	rows, err := db.Query(query, params...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var (
+		counts = make(map[int]map[string]int, len(users))
+		grades = make(map[int]map[string]string, len(users))
+	)
+	for _, u := range users {
+		counts[u] = make(map[string]int)
+		grades[u] = make(map[string]string)
+	}
+	for rows.Next() {
+		var (
+			user                    int
+			md5                     string
+			mods                    int
+			acc                     float64
+			c300, c100, c50, misses int
+		)
+		err := rows.Scan(&user, &md5, &mods, &acc, &c300, &c100, &c50, &misses)
+		if err != nil {
+			return nil, err
+		}
+		if counts[user] == nil {
+			continue
+		}
+		g := scoreGrade(mode, mods, acc, c300, c100, c50, misses)
+		grades[user][md5] = g
+		counts[user][g]++
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	_, err = r.Pipelined(func(p *redis.Pipeline) error {
+		for _, u := range users {
+			key := gradesKey(mode, sm, u)
+			p.Del(key, key+":beatmaps")
+			// A placeholder field keeps the hash around for users with no
+			// scores.
+			fields := map[string]string{"_": "0"}
+			for g, c := range counts[u] {
+				fields[g] = strconv.Itoa(c)
+			}
+			p.HMSet(key, fields)
+			if len(grades[u]) > 0 {
+				p.HMSet(key+":beatmaps", grades[u])
+			}
+			p.Expire(key, gradesExpiration)
+			p.Expire(key+":beatmaps", gradesExpiration)
+		}
+		return nil
+	})
+	return counts, err
+}
+
+// UpdateUserGrades updates the grade counts of the user who submitted a
+// score, if it is their new best score on the beatmap.
+func UpdateUserGrades(db *sqlx.DB, r *redis.Client, scoreID int) error {
+	var (
+		user, mode, smode, mods, completed int
+		md5                                string
+		acc                                float64
+		c300, c100, c50, misses            int
+	)
+	err := db.QueryRow(`SELECT
+			userid, play_mode, special_mode, completed, beatmap_md5,
+			mods, accuracy, 300_count, 100_count, 50_count, misses_count
+		FROM scores_master WHERE id = ? LIMIT 1`, scoreID).Scan(
+		&user, &mode, &smode, &completed, &md5,
+		&mods, &acc, &c300, &c100, &c50, &misses,
+	)
+	sm, ok := common.GetSpecialMode(smode)
+	if err != nil || completed != 3 || !ok {
+		return err
+	}
+	key := gradesKey(mode, sm, user)
+	res, err := updateGrades.Run(r, []string{key, key + ":beatmaps"},
+		md5, scoreGrade(mode, mods, acc, c300, c100, c50, misses)).Result()
+	if err != nil || res == int64(1) {
+		return err
+	}
+	_, err = countUserGrades(db, r, user, mode, sm)
+	return err
+}
+
+// UserGrades retrieves the grade counts of an user in a mode from redis,
+// counting them if they are not there.
+func UserGrades(db *sqlx.DB, r *redis.Client, user, mode int, sm common.SpecialMode) (*GradeCounts, error) {
+	h, err := r.HGetAll(gradesKey(mode, sm, user)).Result()
+	if err != nil {
+		return nil, err
+	}
+	if len(h) == 0 {
+		counts, err := countUserGrades(db, r, user, mode, sm)
+		if err != nil {
+			return nil, err
+		}
+		return newGradeCounts(counts), nil
+	}
+	return parseGradeCounts(h), nil
+}
+
+// gradesCountLimit is how many users missing from redis cachedUserGrades
+// counts the grades of right away.
+const gradesCountLimit = 50
+
+// cachedUserGrades retrieves the grade counts of many users in a mode from
+// redis at once. The grades of the users who are not in redis are counted from
+// the database, up to gradesCountLimit of them: those of the others are left
+// out of the result and counted in the background, so that a big page with a
+// cold cache doesn't take too long, and they are there for the next requests.
+func cachedUserGrades(db *sqlx.DB, r *redis.Client, users []int, mode int, sm common.SpecialMode) (map[int]*GradeCounts, error) {
+	cmds := make([]*redis.StringStringMapCmd, len(users))
+	_, err := r.Pipelined(func(p *redis.Pipeline) error {
+		for i, u := range users {
+			cmds[i] = p.HGetAll(gradesKey(mode, sm, u))
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	grades := make(map[int]*GradeCounts, len(users))
+	var missing []int
+	for i, u := range users {
+		if h := cmds[i].Val(); len(h) > 0 {
+			grades[u] = parseGradeCounts(h)
+		} else {
+			missing = append(missing, u)
+		}
+	}
+	if len(missing) > gradesCountLimit {
+		later := missing[gradesCountLimit:]
+		missing = missing[:gradesCountLimit]
+		go func() {
+			for _, u := range later {
+				if _, err := countUserGrades(db, r, u, mode, sm); err != nil {
+					common.GenericError(err)
+				}
+			}
+		}()
+	}
+	if len(missing) > 0 {
+		counts, err := countGrades(db, r, missing, mode, sm)
+		for u, c := range counts {
+			grades[u] = newGradeCounts(c)
+		}
+		if err != nil {
+			return grades, err
+		}
+	}
+	return grades, nil
+}
+
+// parseGradeCounts reads the grade counts from their hash in redis.
+func parseGradeCounts(h map[string]string) *GradeCounts {
+	counts := make(map[string]int, len(h))
+	for g, c := range h {
+		counts[g] = common.Int(c)
+	}
+	return newGradeCounts(counts)
+}
+
+// newGradeCounts makes a GradeCounts from the counts of each grade, as
+// returned by getrank.GetRank.
+func newGradeCounts(counts map[string]int) *GradeCounts {
+	return &GradeCounts{
+		XH: counts["sshd"],
+		X:  counts["ss"],
+		SH: counts["shd"],
+		S:  counts["s"],
+		A:  counts["a"],
+	}
+}
+
+// setUserGrades sets the grade counts of an user in the modeData of each
+// mode.
+func setUserGrades(db *sqlx.DB, r *redis.Client, user int, sm common.SpecialMode, modes [4]*modeData) error {
+	for mode, m := range modes {
+		g, err := UserGrades(db, r, user, mode, sm)
+		if err != nil {
+			return err
+		}
+		m.Grades = g
+	}
+	return nil
+}
@@ -0,0 +1,59 @@
+package v1
+
+import (
+	"database/sql/driver"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/osu-datenshi/api/common"
+	"github.com/osu-datenshi/api/internal/fakesql"
+	"gopkg.in/redis.v5"
+)
+
+func Test_cachedUserGrades(t *testing.T) {
+	s, err := miniredis.Run()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+	r := redis.NewClient(&redis.Options{Addr: s.Addr()})
+	defer r.Close()
+	i := func(x int64) driver.Value { return x }
+	db, queries := fakesql.Open(fakesql.Result{
+		Match:   "FROM scores_master",
+		Columns: []string{"userid", "beatmap_md5", "mods", "accuracy", "300_count", "100_count", "50_count", "misses_count"},
+		Rows: [][]driver.Value{
+			{i(2), "a", i(0), 100.0, i(300), i(0), i(0), i(0)},
+			{i(2), "b", i(8), 100.0, i(300), i(0), i(0), i(0)},
+		},
+	})
+	defer db.Close()
+
+	sm := common.SpecialModes[0]
+	s.HSet(gradesKey(0, sm, 1), "_", "0")
+	s.HSet(gradesKey(0, sm, 1), "a", "4")
+
+	grades, err := cachedUserGrades(db, r, []int{1, 2, 3}, 0, sm)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[int]*GradeCounts{
+		1: {A: 4},
+		2: {X: 1, XH: 1},
+		3: {},
+	}
+	if !reflect.DeepEqual(grades, want) {
+		t.Errorf("cachedUserGrades() = %v, want %v", grades, want)
+	}
+	if len(*queries) != 1 || !strings.Contains((*queries)[0].Query, "userid IN (?, ?)") {
+		t.Errorf("queries = %v, want the users missing from redis counted at once", *queries)
+	}
+	if g := s.HGet(gradesKey(0, sm, 2), "sshd"); g != "1" {
+		t.Errorf("the grades of the user counted are not in redis: sshd = %q, want 1", g)
+	}
+	if !s.Exists(gradesKey(0, sm, 3)) {
+		t.Error("the grades of the user without scores are not in redis")
+	}
+}
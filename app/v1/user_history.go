@@ -0,0 +1,221 @@
+package v1
+
+import (
+	"database/sql"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/osu-datenshi/api/common"
+	"gopkg.in/redis.v5"
+)
+
+// userHistoryBatch is the number of rows inserted at once by
+// SnapshotUserHistory.
+const userHistoryBatch = 500
+
+type userHistoryEntry struct {
+	Date        common.UnixTimestamp `json:"date"`
+	PP          int                  `json:"pp"`
+	GlobalRank  *int                 `json:"global_rank"`
+	CountryRank *int                 `json:"country_rank"`
+	RankedScore int64                `json:"ranked_score"`
+	PlayCount   int                  `json:"playcount"`
+	Accuracy    float64              `json:"accuracy"`
+}
+
+// historyDay returns the start of the day (UTC) of t.
+func historyDay(t time.Time) time.Time {
+	return t.UTC().Truncate(24 * time.Hour)
+}
+
+// SnapshotUserHistory records the current stats and ranks of all the public
+// users who played in a mode into users_history, as the snapshot of the
+// current day. Taking a snapshot again on the same day replaces it.
+func SnapshotUserHistory(db *sqlx.DB, r *redis.Client, mode int, sm common.SpecialMode) error {
+	m := modesToReadable[mode]
+	rows, err := db.Query(fmt.Sprintf(`SELECT
+			st.id, us.country, st.pp_%[1]s, st.ranked_score_%[1]s,
+			st.playcount_%[1]s, st.avg_accuracy_%[1]s
+		FROM %[2]s st
+		INNER JOIN users ON users.id = st.id
+		INNER JOIN users_stats us ON us.id = st.id
+		WHERE users.privileges & 1 = 1 AND st.playcount_%[1]s > 0`, m, sm.StatsTable))
+	if err != nil {
+		return err
+	}
+	var (
+		entries   []userHistoryEntry
+		users     []int
+		countries []string
+	)
+	for rows.Next() {
+		var (
+			e       userHistoryEntry
+			id      int
+			country string
+		)
+		err := rows.Scan(&id, &country, &e.PP, &e.RankedScore, &e.PlayCount, &e.Accuracy)
+		if err != nil {
+			rows.Close()
+			return err
+		}
+		entries = append(entries, e)
+		users = append(users, id)
+		countries = append(countries, strings.ToLower(country))
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+	if len(users) == 0 {
+		return nil
+	}
+
+	// Look up the ranks of everyone in a single round trip.
+	key := sm.LeaderboardKey() + m
+	ranks := make([]*redis.IntCmd, 0, len(users)*2)
+	_, err = r.Pipelined(func(p *redis.Pipeline) error {
+		for i, id := range users {
+			ranks = append(ranks,
+				p.ZRevRank(key, strconv.Itoa(id)),
+				p.ZRevRank(key+":"+countries[i], strconv.Itoa(id)))
+		}
+		return nil
+	})
+	if err != nil && err != redis.Nil {
+		return err
+	}
+	rank := func(c *redis.IntCmd) *int {
+		if c.Err() != nil {
+			return nil
+		}
+		x := int(c.Val()) + 1
+		return &x
+	}
+
+	day := historyDay(time.Now()).Unix()
+	for start := 0; start < len(entries); start += userHistoryBatch {
+		end := start + userHistoryBatch
+		if end > len(entries) {
+			end = len(entries)
+		}
+		values := make([]string, 0, end-start)
+		params := make([]interface{}, 0, (end-start)*10)
+		for i := start; i < end; i++ {
+			e := entries[i]
+			values = append(values, "(?, ?, ?, ?, ?, ?, ?, ?, ?, ?)")
+			params = append(params, users[i], mode, sm.ID, day, e.PP,
+				rank(ranks[i*2]), rank(ranks[i*2+1]), e.RankedScore, e.PlayCount, e.Accuracy)
+		}
+		_, err := db.Exec(`INSERT INTO users_history
+				(user_id, mode, special_mode, date, pp, global_rank, country_rank,
+				ranked_score, playcount, accuracy)
+			VALUES `+strings.Join(values, ", ")+`
+			ON DUPLICATE KEY UPDATE
+				pp = VALUES(pp), global_rank = VALUES(global_rank),
+				country_rank = VALUES(country_rank), ranked_score = VALUES(ranked_score),
+				playcount = VALUES(playcount), accuracy = VALUES(accuracy)`, params...)
+		if err != nil {
+			return err
+		}
+	}
+	return updatePeaksFromHistory(db, mode, sm, day)
+}
+
+// userHistoryLockExpiration is how long the API instances remember in redis
+// that the snapshot of a day has been taken.
+const userHistoryLockExpiration = 25 * time.Hour
+
+// SnapshotUserHistoryDaily takes a snapshot of the stats of the users in all
+// the modes at the start of every day (UTC).
+func SnapshotUserHistoryDaily(db *sqlx.DB, r *redis.Client) {
+	for {
+		now := time.Now()
+		day := historyDay(now).Add(24 * time.Hour)
+		time.Sleep(day.Sub(now))
+		snapshotUserHistoryDay(db, r, day)
+	}
+}
+
+// snapshotUserHistoryDay takes the snapshots of a day in all the modes. Every
+// API instance tries to take them, so only the first one claiming the day in
+// redis does. If redis can't be reached, the snapshots are taken anyway, as
+// taking them twice only replaces them.
+func snapshotUserHistoryDay(db *sqlx.DB, r *redis.Client, day time.Time) {
+	claimed, err := r.SetNX("api:user_history:"+day.Format("2006-01-02"), 1, userHistoryLockExpiration).Result()
+	if err != nil {
+		fmt.Println("SnapshotUserHistory error", err)
+		common.GenericError(err)
+	} else if !claimed {
+		return
+	}
+	for mode := range modesToReadable {
+		for _, sm := range common.SpecialModes {
+			err := SnapshotUserHistory(db, r, mode, sm)
+			if err != nil {
+				fmt.Println("SnapshotUserHistory error", err)
+				common.GenericError(err)
+			}
+		}
+	}
+}
+
+type userHistoryResponse struct {
+	common.ResponseBase
+	ID          int                `json:"id"`
+	Mode        int                `json:"mode"`
+	SpecialMode int                `json:"special_mode"`
+	History     []userHistoryEntry `json:"history"`
+}
+
+// UserHistoryGET retrieves the daily snapshots of the pp and ranks of an user
+// in a mode over the last days (30 by default, at most 365), oldest first.
+func UserHistoryGET(md common.MethodData) common.CodeMessager {
+	shouldRet, whereClause, param := whereClauseUser(md, "users")
+	if shouldRet != nil {
+		return *shouldRet
+	}
+	r := userHistoryResponse{
+		Mode:        common.InString(0, md.Query("mode"), 3, 0),
+		SpecialMode: md.SpecialMode().ID,
+		History:     []userHistoryEntry{},
+	}
+	days := common.InString(1, md.Query("days"), 365, 30)
+
+	err := md.DB.QueryRow("SELECT id FROM users WHERE "+whereClause+" AND "+
+		md.User.OnlyUserPublic(true)+" LIMIT 1", param).Scan(&r.ID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return common.SimpleResponse(404, "That user could not be found!")
+		}
+		md.Err(err)
+		return Err500
+	}
+
+	since := historyDay(time.Now()).AddDate(0, 0, -days).Unix()
+	rows, err := md.DB.Query(`SELECT
+			date, pp, global_rank, country_rank, ranked_score, playcount, accuracy
+		FROM users_history
+		WHERE user_id = ? AND mode = ? AND special_mode = ? AND date > ?
+		ORDER BY date ASC`, r.ID, r.Mode, r.SpecialMode, since)
+	if err != nil {
+		md.Err(err)
+		return Err500
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var e userHistoryEntry
+		err := rows.Scan(&e.Date, &e.PP, &e.GlobalRank, &e.CountryRank,
+			&e.RankedScore, &e.PlayCount, &e.Accuracy)
+		if err != nil {
+			md.Err(err)
+			continue
+		}
+		r.History = append(r.History, e)
+	}
+	r.Code = 200
+	return r
+}
@@ -0,0 +1,40 @@
+package v1
+
+import (
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/osu-datenshi/api/internal/fakesql"
+	"gopkg.in/redis.v5"
+)
+
+func Test_snapshotUserHistoryDay(t *testing.T) {
+	s, err := miniredis.Run()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+	r := redis.NewClient(&redis.Options{Addr: s.Addr()})
+	defer r.Close()
+	db, queries := fakesql.Open()
+	defer db.Close()
+
+	day := time.Date(2020, 1, 2, 0, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name     string
+		day      time.Time
+		snapshot bool
+	}{
+		{"first", day, true},
+		{"same day", day, false},
+		{"next day", day.AddDate(0, 0, 1), true},
+	}
+	for _, tt := range tests {
+		*queries = nil
+		snapshotUserHistoryDay(db, r, tt.day)
+		if got := len(*queries) > 0; got != tt.snapshot {
+			t.Errorf("%q. snapshot taken = %v, want %v", tt.name, got, tt.snapshot)
+		}
+	}
+}
@@ -11,7 +11,8 @@ type mostPlayedBeatmap struct {
 
 type mostPlayedBeatmapResponse struct {
 	common.ResponseBase
-	Beatmaps []mostPlayedBeatmap `json:"beatmaps"`
+	Beatmaps   []mostPlayedBeatmap `json:"beatmaps"`
+	NextCursor string              `json:"next_cursor,omitempty"`
 }
 
 func UserMostPlayedGET(md common.MethodData) common.CodeMessager {
@@ -20,6 +21,13 @@ func UserMostPlayedGET(md common.MethodData) common.CodeMessager {
 		return *shouldRet
 	}
 	whereClause += " " + genModeClauseColumn(md, "users_beatmap_playcount.game_mode")
+	page, ok := common.KeysetPaginate(md, common.Keyset{
+		Column:   "users_beatmap_playcount.playcount",
+		IDColumn: "users_beatmap_playcount.beatmap_id",
+	}, "ORDER BY users_beatmap_playcount.playcount DESC", 100)
+	if !ok {
+		return ErrCursor
+	}
 	var q = `SELECT
 beatmap_id, beatmapset_id, beatmap_md5,
 song_name, ar, od, difficulty_std, difficulty_taiko,
@@ -28,9 +36,8 @@ hit_length, ranked, ranked_status_freezed,
 latest_update, users_beatmap_playcount.playcount 
 FROM users_beatmap_playcount LEFT JOIN beatmaps USING(beatmap_id) 
 LEFT JOIN users ON users_beatmap_playcount.user_id = users.id 
-WHERE ` + whereClause + ` ORDER BY users_beatmap_playcount.playcount DESC ` +
-		common.Paginate(md.Query("p"), md.Query("l"), 100)
-	rows, err := md.DB.Query(q, param)
+WHERE ` + whereClause + ` AND ` + page.Where + ` ` + page.OrderBy + page.Limit
+	rows, err := md.DB.Query(q, append([]interface{}{param}, page.Params...)...)
 	if err != nil {
 		md.Err(err)
 		return Err500
@@ -52,6 +59,10 @@ WHERE ` + whereClause + ` ORDER BY users_beatmap_playcount.playcount DESC ` +
 		}
 		r.Beatmaps = append(r.Beatmaps, mpb)
 	}
+	if len(r.Beatmaps) > 0 {
+		last := r.Beatmaps[len(r.Beatmaps)-1]
+		r.NextCursor = page.Next(len(r.Beatmaps), last.PlayCount, last.Beatmap.BeatmapID)
+	}
 	r.Code = 200
 	return r
 }
@@ -0,0 +1,119 @@
+package v1
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/osu-datenshi/api/common"
+	"gopkg.in/redis.v5"
+)
+
+// peakData is the best pp and ranks an user ever reached in a mode.
+type peakData struct {
+	PP              int                   `json:"pp"`
+	PPDate          common.UnixTimestamp  `json:"pp_date"`
+	GlobalRank      *int                  `json:"global_rank"`
+	GlobalRankDate  *common.UnixTimestamp `json:"global_rank_date"`
+	CountryRank     *int                  `json:"country_rank"`
+	CountryRankDate *common.UnixTimestamp `json:"country_rank_date"`
+}
+
+// updatePeaksClause keeps the best of the stored and the new values in
+// users_peaks. The dates are set before the values they go with, as MySQL
+// assigns the columns from left to right. The columns are qualified, as they
+// would be ambiguous when inserting from users_history.
+const updatePeaksClause = `ON DUPLICATE KEY UPDATE
+	users_peaks.pp_date = IF(VALUES(pp) > users_peaks.pp, VALUES(pp_date), users_peaks.pp_date),
+	users_peaks.pp = GREATEST(users_peaks.pp, VALUES(pp)),
+	users_peaks.global_rank_date = IF(VALUES(global_rank) < IFNULL(users_peaks.global_rank, 4294967295),
+		VALUES(global_rank_date), users_peaks.global_rank_date),
+	users_peaks.global_rank = IF(VALUES(global_rank) < IFNULL(users_peaks.global_rank, 4294967295),
+		VALUES(global_rank), users_peaks.global_rank),
+	users_peaks.country_rank_date = IF(VALUES(country_rank) < IFNULL(users_peaks.country_rank, 4294967295),
+		VALUES(country_rank_date), users_peaks.country_rank_date),
+	users_peaks.country_rank = IF(VALUES(country_rank) < IFNULL(users_peaks.country_rank, 4294967295),
+		VALUES(country_rank), users_peaks.country_rank)`
+
+// UpdateUserPeaks compares the current pp and ranks of an user in a mode with
+// their peaks, and updates the peaks they have beaten.
+func UpdateUserPeaks(db *sqlx.DB, r *redis.Client, user, mode int, sm common.SpecialMode) error {
+	m := modesToReadable[mode]
+	var (
+		pp      int
+		country string
+	)
+	err := db.QueryRow(fmt.Sprintf(`SELECT st.pp_%[1]s, us.country
+		FROM %[2]s st
+		INNER JOIN users ON users.id = st.id
+		INNER JOIN users_stats us ON us.id = st.id
+		WHERE st.id = ? AND users.privileges & 1 = 1`, m, sm.StatsTable), user).Scan(&pp, &country)
+	switch {
+	case err == sql.ErrNoRows:
+		return nil
+	case err != nil:
+		return err
+	}
+
+	key := sm.LeaderboardKey() + m
+	global := _position(r, key, user)
+	countryRank := _position(r, key+":"+strings.ToLower(country), user)
+	now := time.Now().Unix()
+	date := func(rank *int) interface{} {
+		if rank == nil {
+			return nil
+		}
+		return now
+	}
+	_, err = db.Exec(`INSERT INTO users_peaks
+			(user_id, mode, special_mode, pp, pp_date, global_rank, global_rank_date,
+			country_rank, country_rank_date)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?) `+updatePeaksClause,
+		user, mode, sm.ID, pp, now, global, date(global), countryRank, date(countryRank))
+	return err
+}
+
+// updatePeaksFromHistory updates the peaks of all the users with the
+// snapshot of a day in users_history, so that the ranks gained without
+// playing (e.g. when someone above is restricted) are tracked as well.
+func updatePeaksFromHistory(db *sqlx.DB, mode int, sm common.SpecialMode, day int64) error {
+	_, err := db.Exec(`INSERT INTO users_peaks
+			(user_id, mode, special_mode, pp, pp_date, global_rank, global_rank_date,
+			country_rank, country_rank_date)
+		SELECT
+			user_id, mode, special_mode, pp, date, global_rank, IF(global_rank IS NULL, NULL, date),
+			country_rank, IF(country_rank IS NULL, NULL, date)
+		FROM users_history
+		WHERE mode = ? AND special_mode = ? AND date = ? `+updatePeaksClause,
+		mode, sm.ID, day)
+	return err
+}
+
+// setUserPeaks sets the peaks of an user in the modeData of each mode.
+func setUserPeaks(db *sqlx.DB, user int, sm common.SpecialMode, modes [4]*modeData) error {
+	rows, err := db.Query(`SELECT
+			mode, pp, pp_date, global_rank, global_rank_date, country_rank, country_rank_date
+		FROM users_peaks
+		WHERE user_id = ? AND special_mode = ?`, user, sm.ID)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var (
+			mode int
+			p    peakData
+		)
+		err := rows.Scan(&mode, &p.PP, &p.PPDate, &p.GlobalRank, &p.GlobalRankDate,
+			&p.CountryRank, &p.CountryRankDate)
+		if err != nil {
+			return err
+		}
+		if mode >= 0 && mode < len(modes) {
+			modes[mode].Peak = &p
+		}
+	}
+	return rows.Err()
+}
@@ -17,48 +17,10 @@ type userScore struct {
 
 type userScoresResponse struct {
 	common.ResponseBase
-	Scores []userScore `json:"scores"`
+	Scores     []userScore `json:"scores"`
+	NextCursor string      `json:"next_cursor,omitempty"`
 }
 
-const masterMode = true
-const masterScoreSelectBase = `
-		SELECT
-			s.id, s.beatmap_md5, s.score,
-			s.max_combo, s.full_combo, s.mods,
-			s.300_count, s.100_count, s.50_count,
-			s.gekis_count, s.katus_count, s.misses_count,
-			s.time, s.play_mode, s.accuracy, s.pp,
-			s.completed,
-
-			b.beatmap_id, b.beatmapset_id, b.beatmap_md5,
-			b.song_name, b.ar, b.od, b.difficulty_std,
-			b.difficulty_taiko, b.difficulty_ctb, b.difficulty_mania,
-			b.max_combo, b.hit_length, b.ranked,
-			b.ranked_status_freezed, b.latest_update
-		FROM scores_master as s
-		INNER JOIN beatmaps as b ON b.beatmap_md5 = s.beatmap_md5
-		INNER JOIN users ON users.id = s.userid
-		`
-
-const relaxScoreSelectBase = `
-		SELECT
-			s.id, s.beatmap_md5, s.score,
-			s.max_combo, s.full_combo, s.mods,
-			s.300_count, s.100_count, s.50_count,
-			s.gekis_count, s.katus_count, s.misses_count,
-			s.time, s.play_mode, s.accuracy, s.pp,
-			s.completed,
-
-			b.beatmap_id, b.beatmapset_id, b.beatmap_md5,
-			b.song_name, b.ar, b.od, b.difficulty_std,
-			b.difficulty_taiko, b.difficulty_ctb, b.difficulty_mania,
-			b.max_combo, b.hit_length, b.ranked,
-			b.ranked_status_freezed, b.latest_update
-		FROM scores_relax as s
-		INNER JOIN beatmaps as b ON b.beatmap_md5 = s.beatmap_md5
-		INNER JOIN users ON users.id = s.userid
-		`
-
 const userScoreSelectBase = `
 		SELECT
 			s.id, s.beatmap_md5, s.score,
@@ -73,7 +35,7 @@ const userScoreSelectBase = `
 			b.difficulty_taiko, b.difficulty_ctb, b.difficulty_mania,
 			b.max_combo, b.hit_length, b.ranked,
 			b.ranked_status_freezed, b.latest_update
-		FROM scores as s
+		FROM scores_master as s
 		INNER JOIN beatmaps as b ON b.beatmap_md5 = s.beatmap_md5
 		INNER JOIN users ON users.id = s.userid
 		`
@@ -87,52 +49,26 @@ func UserScoresBestGET(md common.MethodData) common.CodeMessager {
 	}
 	
 	mc := genModeClause(md)
-	smode := common.Int(md.Query("smode"))
-	if smode < 0 || smode > 2 {
-		// enforce to vanilla
-		smode = 0
-	}
-	if !md.HasQuery("smode") && common.Int(md.Query("rx")) > 0 {
-		// retain old behavior
-		smode = 1
+	page, ok := common.KeysetPaginate(md, common.Keyset{Column: "s.pp", IDColumn: "s.id"},
+		"ORDER BY s.pp DESC, s.score DESC", 100)
+	if !ok {
+		return ErrCursor
 	}
+	params := append([]interface{}{param}, page.Params...)
 	// For all modes that have PP, we leave out 0 PP scores.
 
-	if masterMode {
-		return masterPuts(md, fmt.Sprintf(
-			`WHERE
-				s.completed = '3'
-				AND %s
-				%s
-				AND s.special_mode = %d
-				AND %s
-			ORDER BY s.pp DESC, s.score DESC %s`,
-			wc, mc, smode, md.User.OnlyUserPublic(true), common.Paginate(md.Query("p"), md.Query("l"), 100),
-		), param)
-	} else {
-		switch smode {
-			case 1:
-				return relaxPuts(md, fmt.Sprintf(
-					`WHERE
-						s.completed = '3'
-						AND %s
-						%s
-						AND %s
-					ORDER BY s.pp DESC, s.score DESC %s`,
-					wc, mc, md.User.OnlyUserPublic(true), common.Paginate(md.Query("p"), md.Query("l"), 100),
-				), param)
-			default:
-				return scoresPuts(md, fmt.Sprintf(
-					`WHERE
-						s.completed = '3'
-						AND %s
-						%s
-						AND %s
-					ORDER BY s.pp DESC, s.score DESC %s`,
-					wc, mc, md.User.OnlyUserPublic(true), common.Paginate(md.Query("p"), md.Query("l"), 100),
-				), param)
-		}
-	}
+	resp := scoresPuts(md, fmt.Sprintf(
+		`WHERE
+			s.completed = '3'
+			AND %s
+			%s
+			AND %s
+			AND %s
+			AND %s
+		%s %s`,
+		wc, mc, md.SpecialMode().ScoresFilter("s"), md.User.OnlyUserPublic(true), page.Where, page.OrderBy, page.Limit,
+	), params...)
+	return withNextCursor(resp, page, func(s userScore) interface{} { return s.PP })
 }
 
 // UserScoresRecentGET retrieves an user's latest scores.
@@ -142,51 +78,39 @@ func UserScoresRecentGET(md common.MethodData) common.CodeMessager {
 		return *cm
 	}
 	mc := genModeClause(md)
-	smode := common.Int(md.Query("smode"))
-	if smode < 0 || smode > 2 {
-		// enforce to vanilla
-		smode = 0
+	page, ok := common.KeysetPaginate(md, common.Keyset{IDColumn: "s.id"}, "ORDER BY s.id DESC", 100)
+	if !ok {
+		return ErrCursor
 	}
-	if !md.HasQuery("smode") && common.Int(md.Query("rx")) > 0 {
-		// retain old behavior
-		smode = 1
-	}
-	if masterMode {
-		return masterPuts(md, fmt.Sprintf(
-			`WHERE
-				%s
-				%s
-				AND s.special_mode = %d
-				AND %s
-			ORDER BY s.id DESC %s`,
-			wc, mc, smode, md.User.OnlyUserPublic(true), common.Paginate(md.Query("p"), md.Query("l"), 100),
-		), param)
-	} else {
-		switch smode {
-			case 1:
-				return relaxPuts(md, fmt.Sprintf(
-					`WHERE
-						%s
-						%s
-						AND `+md.User.OnlyUserPublic(true)+`
-					ORDER BY s.id DESC %s`,
-					wc, mc, common.Paginate(md.Query("p"), md.Query("l"), 100),
-				), param)
-		  default:
-				return scoresPuts(md, fmt.Sprintf(
-					`WHERE
-						%s
-						%s
-						AND `+md.User.OnlyUserPublic(true)+`
-					ORDER BY s.id DESC %s`,
-					wc, mc, common.Paginate(md.Query("p"), md.Query("l"), 100),
-				), param)
-		}
+	params := append([]interface{}{param}, page.Params...)
+
+	resp := scoresPuts(md, fmt.Sprintf(
+		`WHERE
+			%s
+			%s
+			AND %s
+			AND %s
+			AND %s
+		%s %s`,
+		wc, mc, md.SpecialMode().ScoresFilter("s"), md.User.OnlyUserPublic(true), page.Where, page.OrderBy, page.Limit,
+	), params...)
+	return withNextCursor(resp, page, func(s userScore) interface{} { return s.ID })
+}
+
+// withNextCursor sets the cursor to the next page of scores in resp, if it
+// is a successful userScoresResponse. key returns the sort key of a score.
+func withNextCursor(resp common.CodeMessager, page common.Page, key func(userScore) interface{}) common.CodeMessager {
+	r, ok := resp.(userScoresResponse)
+	if !ok || len(r.Scores) == 0 {
+		return resp
 	}
+	last := r.Scores[len(r.Scores)-1]
+	r.NextCursor = page.Next(len(r.Scores), key(last), last.ID)
+	return r
 }
 
 func genericPuts(rows *sql.Rows, md common.MethodData) common.CodeMessager {
-	err := nil
+	var err error
 	var scores []userScore
 	for rows.Next() {
 		var (
@@ -230,15 +154,6 @@ func genericPuts(rows *sql.Rows, md common.MethodData) common.CodeMessager {
 	return r
 }
 
-func masterPuts(md common.MethodData, whereClause string, params ...interface{}) common.CodeMessager {
-	rows, err := md.DB.Query(masterScoreSelectBase+whereClause, params...)
-	if err != nil {
-		md.Err(err)
-		return Err500
-	}
-	return genericPuts(rows, md)
-}
-
 func scoresPuts(md common.MethodData, whereClause string, params ...interface{}) common.CodeMessager {
 	rows, err := md.DB.Query(userScoreSelectBase+whereClause, params...)
 	if err != nil {
@@ -247,12 +162,3 @@ func scoresPuts(md common.MethodData, whereClause string, params ...interface{})
 	}
 	return genericPuts(rows, md)
 }
-
-func relaxPuts(md common.MethodData, whereClause string, params ...interface{}) common.CodeMessager {
-	rows, err := md.DB.Query(relaxScoreSelectBase+whereClause, params...)
-	if err != nil {
-		md.Err(err)
-		return Err500
-	}
-	return genericPuts(rows, md)
-}
@@ -0,0 +1,20 @@
+package v2
+
+import (
+	"github.com/osu-datenshi/api/app/v1"
+	"github.com/osu-datenshi/api/common"
+)
+
+// BeatmapGET retrieves a beatmap.
+// GET /beatmaps/:id
+func BeatmapGET(md common.MethodData) common.CodeMessager {
+	return withID(md, "b", v1.BeatmapGET)
+}
+
+// BeatmapScoresGET retrieves the top scores of a beatmap.
+// GET /beatmaps/:id/scores
+func BeatmapScoresGET(md common.MethodData) common.CodeMessager {
+	// md5 would take precedence over b in v1.ScoresGET
+	md.Ctx.QueryArgs().Del("md5")
+	return withID(md, "b", v1.ScoresGET)
+}
@@ -0,0 +1,12 @@
+package v2
+
+import (
+	"github.com/osu-datenshi/api/app/v1"
+	"github.com/osu-datenshi/api/common"
+)
+
+// ClanMembersGET retrieves the members of a clan.
+// GET /clans/:id/members
+func ClanMembersGET(md common.MethodData) common.CodeMessager {
+	return withID(md, "id", v1.ClanMembersGET)
+}
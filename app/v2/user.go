@@ -0,0 +1,35 @@
+package v2
+
+import (
+	"github.com/osu-datenshi/api/app/v1"
+	"github.com/osu-datenshi/api/common"
+)
+
+// withUserID is like withID, but it also allows "self" to be used in place of
+// the ID. Users can only be addressed by ID, so the name parameter is dropped.
+func withUserID(md common.MethodData, f func(md common.MethodData) common.CodeMessager) common.CodeMessager {
+	md.Ctx.QueryArgs().Del("name")
+	if id, _ := md.Ctx.UserValue("id").(string); id == "self" {
+		md.Ctx.QueryArgs().Set("id", id)
+		return f(md)
+	}
+	return withID(md, "id", f)
+}
+
+// UserGET retrieves all of an user's information.
+// GET /users/:id
+func UserGET(md common.MethodData) common.CodeMessager {
+	return withUserID(md, v1.UserFullGET)
+}
+
+// UserScoresBestGET retrieves the best scores of an user.
+// GET /users/:id/scores/best
+func UserScoresBestGET(md common.MethodData) common.CodeMessager {
+	return withUserID(md, v1.UserScoresBestGET)
+}
+
+// UserScoresRecentGET retrieves the latest scores of an user.
+// GET /users/:id/scores/recent
+func UserScoresRecentGET(md common.MethodData) common.CodeMessager {
+	return withUserID(md, v1.UserScoresRecentGET)
+}
@@ -0,0 +1,33 @@
+// Package v2 implements the second version of the Ripple API. It exposes the
+// same data as v1, but resources are addressed by their ID in the request
+// path, rather than through the querystring.
+package v2
+
+import (
+	"strconv"
+
+	"github.com/osu-datenshi/api/common"
+)
+
+var errNotFound = common.SimpleResponse(404, "That resource could not be found.")
+
+// pathID retrieves the :id parameter in the request path, making sure it is a
+// valid ID.
+func pathID(md common.MethodData) (string, bool) {
+	id, _ := md.Ctx.UserValue("id").(string)
+	if _, err := strconv.Atoi(id); err != nil {
+		return "", false
+	}
+	return id, true
+}
+
+// withID calls the v1 method f, passing the :id parameter in the request path
+// as the querystring parameter param.
+func withID(md common.MethodData, param string, f func(md common.MethodData) common.CodeMessager) common.CodeMessager {
+	id, ok := pathID(md)
+	if !ok {
+		return errNotFound
+	}
+	md.Ctx.QueryArgs().Set(param, id)
+	return f(md)
+}
@@ -0,0 +1,87 @@
+package app
+
+import (
+	"encoding/json"
+
+	"github.com/osu-datenshi/api/common"
+	"github.com/valyala/fasthttp"
+)
+
+// V2Method wraps an API method to a HandlerFunc for the v2 API. Unlike Method,
+// the resource is presented without the code/message wrapper, and errors are
+// reported through the HTTP status code along with a v2ErrorResponse.
+func V2Method(f func(md common.MethodData) common.CodeMessager, privilegesNeeded ...int) fasthttp.RequestHandler {
+	return func(c *fasthttp.RequestCtx) {
+		md, doggoTags := methodData(c)
+
+		doggo.Incr("requests.v2", doggoTags, 1)
+
+		c.Response.Header.SetContentType("application/json; charset=utf-8")
+
+		if !methodAllowed(md) {
+			doggo.Incr("requests.v2.limited", doggoTags, 1)
+			v2Error(c, 429, "Too many requests, slow down.")
+			return
+		}
+
+		missing := missingPrivileges(md, privilegesNeeded)
+		if missing != 0 {
+			v2Error(c, 403, "You don't have the privilege(s): "+common.Privileges(missing).String()+".")
+			return
+		}
+
+		resp := f(md)
+		code := resp.GetCode()
+		if code >= 400 {
+			v2Error(c, code, resp.GetMessage())
+			return
+		}
+		// some v1 methods forget to set the code on success.
+		if code == 0 {
+			code = 200
+		}
+
+		resource, err := unwrapResponse(resp)
+		if err != nil {
+			common.Err(c, err)
+			v2Error(c, 500, "")
+			return
+		}
+		c.SetStatusCode(code)
+		mkjson(c, resource)
+	}
+}
+
+// v2ErrorResponse is the body of every v2 response with an error status code.
+type v2ErrorResponse struct {
+	Status  int    `json:"status"`
+	Message string `json:"message"`
+}
+
+func v2Error(c *fasthttp.RequestCtx, code int, message string) {
+	if message == "" {
+		message = fasthttp.StatusMessage(code)
+	}
+	c.SetStatusCode(code)
+	mkjson(c, v2ErrorResponse{
+		Status:  code,
+		Message: message,
+	})
+}
+
+// unwrapResponse strips the fields of common.ResponseBase from a response
+// returned by a v1 method, so that only the resource itself is left.
+func unwrapResponse(resp common.CodeMessager) (map[string]json.RawMessage, error) {
+	data, err := json.Marshal(resp)
+	if err != nil {
+		return nil, err
+	}
+	var resource map[string]json.RawMessage
+	err = json.Unmarshal(data, &resource)
+	if err != nil {
+		return nil, err
+	}
+	delete(resource, "code")
+	delete(resource, "message")
+	return resource, nil
+}
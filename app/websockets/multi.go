@@ -1,9 +1,18 @@
 package websockets
 
 import (
+	"crypto/sha1"
+	"database/sql"
+	"encoding/hex"
 	"encoding/json"
 	"fmt"
+	"sort"
+	"strconv"
 	"sync"
+	"time"
+
+	"github.com/osu-datenshi/api/common"
+	"gopkg.in/thehowl/go-osuapi.v1"
 )
 
 // SubscribeMultiMatches subscribes to receiving information from completed
@@ -46,12 +55,196 @@ func matchRetriever() {
 
 func handleNewMultiGame(payload string) {
 	defer catchPanic()
+	data := json.RawMessage(payload)
+	// Clients need the ID of the match given by the API to retrieve it through
+	// get_match, so it is added to what pep.py sent.
+	if id := multiGameMatchID(payload); id != 0 {
+		var fields map[string]json.RawMessage
+		if json.Unmarshal(data, &fields) == nil {
+			fields["match_id"] = json.RawMessage(strconv.FormatInt(id, 10))
+			data, _ = json.Marshal(fields)
+		}
+	}
+
 	multiSubscriptionsMtx.RLock()
 	cp := make([]*conn, len(multiSubscriptions))
 	copy(cp, multiSubscriptions)
 	multiSubscriptionsMtx.RUnlock()
 
 	for _, el := range cp {
-		el.WriteJSON(TypeNewMatch, json.RawMessage(payload))
+		el.WriteJSON(TypeNewMatch, data)
+	}
+}
+
+// matchIdleTime is how long a match can go without completing a game before
+// a game with the same pep.py ID and name is considered to be of a new match.
+// pep.py reuses its IDs after restarting, so a game is only added to a stored
+// match if it has the same name and it is not idle.
+const matchIdleTime = 30 * time.Minute
+
+// multiGame is a completed game of a multiplayer match, as published by
+// pep.py on api:mp_complete_match when all the players of the match have
+// completed it (match.allPlayersCompleted).
+type multiGame struct {
+	ID        int                       `json:"id"`
+	Name      string                    `json:"name"`
+	BeatmapID int                       `json:"beatmap_id"`
+	Mods      int                       `json:"mods"`
+	GameMode  int                       `json:"game_mode"`
+	Scores    map[string]multiGameScore `json:"scores"`
+}
+
+type multiGameScore struct {
+	Score  int64 `json:"score"`
+	Mods   int   `json:"mods"`
+	Failed bool  `json:"failed"`
+	Pass   bool  `json:"pass"`
+	Team   int   `json:"team"`
+}
+
+// multiGameExpiration is how long the API instances remember a completed game
+// in redis, to tell each other that it has been stored.
+const multiGameExpiration = time.Minute
+
+// multiGameMatchID stores a completed game, and returns the ID of its match in
+// the database. Every API instance receives the games, so only the first one
+// claiming a game in redis stores it, while the others wait for it to tell them
+// the ID of the match. 0 is returned if the game could not be stored.
+func multiGameMatchID(payload string) int64 {
+	sum := sha1.Sum([]byte(payload))
+	key := "api:mp_game:" + hex.EncodeToString(sum[:])
+	claimed, err := red.SetNX(key, 0, multiGameExpiration).Result()
+	if err != nil {
+		common.WSErr(err)
+		return 0
+	}
+	if claimed {
+		id := storeMultiGame(payload)
+		if id != 0 {
+			if err := red.Set(key, id, multiGameExpiration).Err(); err != nil {
+				common.WSErr(err)
+			}
+		}
+		return id
+	}
+	for i := 0; i < 50; i++ {
+		if id, _ := red.Get(key).Int64(); id != 0 {
+			return id
+		}
+		time.Sleep(100 * time.Millisecond)
+	}
+	return 0
+}
+
+// storeMultiGame saves a completed game in the database, so that the match
+// can later be retrieved through get_match. It returns the ID of the match in
+// the database, or 0 if the game could not be saved.
+func storeMultiGame(payload string) int64 {
+	var g multiGame
+	err := json.Unmarshal([]byte(payload), &g)
+	if err != nil {
+		common.WSErr(err)
+		return 0
+	}
+
+	// The scores are keyed by user ID and pep.py does not tell us the slots,
+	// so we assign them in order of user ID.
+	userIDs := make([]int, 0, len(g.Scores))
+	for k := range g.Scores {
+		id, err := strconv.Atoi(k)
+		if err != nil {
+			continue
+		}
+		userIDs = append(userIDs, id)
+	}
+	sort.Ints(userIDs)
+
+	now := time.Now().Unix()
+	tx, err := db.Begin()
+	if err != nil {
+		common.WSErr(err)
+		return 0
+	}
+	start := multiGameStart(tx, g, now)
+	matchID, err := multiMatchID(tx, g, start, now)
+	if err != nil {
+		tx.Rollback()
+		common.WSErr(err)
+		return 0
+	}
+	res, err := tx.Exec(`INSERT INTO match_games(match_id, beatmap_id, play_mode, mods, start_time, end_time)
+		VALUES (?, ?, ?, ?, ?, ?)`,
+		matchID, g.BeatmapID, g.GameMode, g.Mods, start, now)
+	if err != nil {
+		tx.Rollback()
+		common.WSErr(err)
+		return 0
+	}
+	gameID, err := res.LastInsertId()
+	if err != nil {
+		tx.Rollback()
+		common.WSErr(err)
+		return 0
+	}
+	for slot, userID := range userIDs {
+		s := g.Scores[strconv.Itoa(userID)]
+		_, err = tx.Exec(`INSERT INTO match_game_scores(game_id, user_id, slot, team, score, mods, pass)
+			VALUES (?, ?, ?, ?, ?, ?, ?)`,
+			gameID, userID, slot, s.Team, s.Score, s.Mods, s.Pass && !s.Failed)
+		if err != nil {
+			tx.Rollback()
+			common.WSErr(err)
+			return 0
+		}
+	}
+	err = tx.Commit()
+	if err != nil {
+		common.WSErr(err)
+		return 0
+	}
+	return matchID
+}
+
+// multiGameStart estimates when a game that ended at end started, from the
+// length of its beatmap, as pep.py only tells when the games are completed.
+// If the beatmap is not known, it returns end.
+func multiGameStart(tx *sql.Tx, g multiGame, end int64) int64 {
+	var length float64
+	err := tx.QueryRow("SELECT hit_length FROM beatmaps WHERE beatmap_id = ? LIMIT 1", g.BeatmapID).Scan(&length)
+	if err != nil {
+		if err != sql.ErrNoRows {
+			common.WSErr(err)
+		}
+		return end
+	}
+	switch mods := osuapi.Mods(g.Mods); {
+	case mods&osuapi.ModDoubleTime != 0:
+		length /= 1.5
+	case mods&osuapi.ModHalfTime != 0:
+		length /= 0.75
+	}
+	return end - int64(length)
+}
+
+// multiMatchID returns the ID of the stored match a game is part of, creating
+// the match if it is a new one.
+func multiMatchID(tx *sql.Tx, g multiGame, start, now int64) (int64, error) {
+	var id int64
+	err := tx.QueryRow(`SELECT id FROM matches
+		WHERE peppy_id = ? AND name = ? AND end_time > ?
+		ORDER BY id DESC LIMIT 1 FOR UPDATE`,
+		g.ID, g.Name, now-int64(matchIdleTime/time.Second)).Scan(&id)
+	switch {
+	case err == sql.ErrNoRows:
+		res, err := tx.Exec("INSERT INTO matches(peppy_id, name, start_time, end_time) VALUES (?, ?, ?, ?)",
+			g.ID, g.Name, start, now)
+		if err != nil {
+			return 0, err
+		}
+		return res.LastInsertId()
+	case err != nil:
+		return 0, err
 	}
+	_, err = tx.Exec("UPDATE matches SET end_time = ? WHERE id = ?", now, id)
+	return id, err
 }
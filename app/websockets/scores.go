@@ -13,8 +13,9 @@ import (
 )
 
 type subscribeScoresUser struct {
-	User  int   `json:"user"`
-	Modes []int `json:"modes"`
+	User         int   `json:"user"`
+	Modes        []int `json:"modes"`
+	SpecialModes []int `json:"special_modes"`
 }
 
 // SubscribeScores subscribes a connection to score updates.
@@ -78,13 +79,15 @@ type scoreUser struct {
 
 type score struct {
 	v1.Score
+	SpecialMode int
 	scoreUser
 }
 
 type scoreJSON struct {
 	v1.Score
-	UserID int       `json:"user_id"`
-	User   scoreUser `json:"user"`
+	SpecialMode int       `json:"special_mode"`
+	UserID      int       `json:"user_id"`
+	User        scoreUser `json:"user"`
 }
 
 func handleNewScore(id string) {
@@ -94,9 +97,9 @@ func handleNewScore(id string) {
 SELECT
 	s.id, s.beatmap_md5, s.score, s.max_combo, s.full_combo, s.mods,
 	s.300_count, s.100_count, s.50_count, s.gekis_count, s.katus_count, s.misses_count,
-	s.time, s.play_mode, s.accuracy, s.pp, s.completed, s.userid AS user_id,
-	u.username, u.privileges
-FROM scores s
+	s.time, s.play_mode, s.accuracy, s.pp, s.completed, s.special_mode,
+	s.userid AS user_id, u.username, u.privileges
+FROM scores_master s
 INNER JOIN users u ON s.userid = u.id
 WHERE s.id = ?`, id)
 	if err != nil {
@@ -114,9 +117,10 @@ WHERE s.id = ?`, id)
 	))
 
 	sj := scoreJSON{
-		Score:  s.Score,
-		UserID: s.UserID,
-		User:   s.scoreUser,
+		Score:       s.Score,
+		SpecialMode: s.SpecialMode,
+		UserID:      s.UserID,
+		User:        s.scoreUser,
 	}
 
 	scoreSubscriptionsMtx.RLock()
@@ -145,6 +149,11 @@ func scoreUserValid(users []subscribeScoresUser, s scoreJSON) bool {
 					return false
 				}
 			}
+			if len(u.SpecialModes) > 0 {
+				if !inModes(u.SpecialModes, s.SpecialMode) {
+					return false
+				}
+			}
 			return true
 		}
 	}
@@ -131,9 +131,10 @@ func Update(b BeatmapDefiningQuality, beatmapInDB bool) error {
 	for i := 0; i <= 3; i++ {
 		mode := osuapi.Mode(i)
 		beatmaps, err := Client.GetBeatmaps(osuapi.GetBeatmapsOpts{
-			BeatmapID:   b.ID,
-			BeatmapHash: b.MD5,
-			Mode:        &mode,
+			BeatmapID:        b.ID,
+			BeatmapHash:      b.MD5,
+			Mode:             &mode,
+			IncludeConverted: true,
 		})
 		if err != nil {
 			return err
@@ -161,18 +162,24 @@ func Update(b BeatmapDefiningQuality, beatmapInDB bool) error {
 		main.Approved = osuapi.ApprovedStatus(b.ranked)
 	}
 	songName := fmt.Sprintf("%s - %s [%s]", main.Artist, main.Title, main.DiffName)
+	var approvedDate int64
+	if t := main.ApprovedDate.GetTime(); !t.IsZero() {
+		approvedDate = t.Unix()
+	}
 	_, err := DB.Exec(`INSERT INTO 
 	beatmaps (
 		beatmap_id, beatmapset_id, beatmap_md5,
 		song_name, ar, od, difficulty_std, difficulty_taiko,
 		difficulty_ctb, difficulty_mania, max_combo, hit_length,
-		bpm, ranked, latest_update, ranked_status_freezed
+		bpm, ranked, latest_update, ranked_status_freezed,
+		creator, tags, genre_id, language_id, source, approved_date
 	) 
-	VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?);`,
+	VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?);`,
 		main.BeatmapID, main.BeatmapSetID, main.FileMD5,
 		songName, main.ApproachRate, main.OverallDifficulty, data[0].DifficultyRating, data[1].DifficultyRating,
 		data[2].DifficultyRating, data[3].DifficultyRating, main.MaxCombo, main.HitLength,
 		int(main.BPM), main.Approved, time.Now().Unix(), b.frozen,
+		main.Creator, main.Tags, int(main.Genre), int(main.Language), main.Source, approvedDate,
 	)
 	if err != nil {
 		return err
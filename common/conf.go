@@ -13,20 +13,49 @@ var Version string
 // Conf is the configuration file data for the ripple API.
 // Conf uses https://github.com/thehowl/conf
 type Conf struct {
-	DatabaseType           string `description:"At the moment, 'mysql' is the only supported database type."`
-	DSN                    string `description:"The Data Source Name for the database. More: https://github.com/go-sql-driver/mysql#dsn-data-source-name"`
-	ListenTo               string `description:"The IP/Port combination from which to take connections, e.g. :8080"`
-	Unix                   bool   `description:"Bool indicating whether ListenTo is a UNIX socket or an address."`
-	SentryDSN              string `description:"thing for sentry whatever"`
-	HanayoKey              string
-	BeatmapRequestsPerUser int
-	RankQueueSize          int
-	OsuAPIKey              string
-	RedisAddr              string
-	RedisPassword          string
-	RedisDB                int
+	DatabaseType                 string `description:"At the moment, 'mysql' is the only supported database type."`
+	DSN                          string `description:"The Data Source Name for the database. More: https://github.com/go-sql-driver/mysql#dsn-data-source-name"`
+	ListenTo                     string `description:"The IP/Port combination from which to take connections, e.g. :8080"`
+	Unix                         bool   `description:"Bool indicating whether ListenTo is a UNIX socket or an address."`
+	SentryDSN                    string `description:"thing for sentry whatever"`
+	HanayoKey                    string
+	BeatmapRequestsPerUser       int
+	RankQueueSize                int
+	OsuAPIKey                    string
+	PeppyRequestsPerMinute       int    `description:"Requests per minute allowed for each key on the osu! API compatibility layer (/api/get_*)."`
+	ReplayFolder                 string `description:"The folder in which the score server saves the replays, e.g. /home/ripple/lets/.data/replays"`
+	ReadRequestsPerMinute        int    `description:"Requests per minute allowed on the GET methods of the API for each IP address, when no token is given. 0 uses the default, -1 disables the limit."`
+	ReadTokenRequestsPerMinute   int    `description:"Requests per minute allowed on the GET methods of the API for each token."`
+	WriteRequestsPerMinute       int    `description:"Requests per minute allowed on the POST methods of the API for each IP address, when no token is given."`
+	WriteTokenRequestsPerMinute  int    `description:"Requests per minute allowed on the POST methods of the API for each token."`
+	SearchRequestsPerMinute      int    `description:"Requests per minute allowed on the search methods of the API (scores/search, clans/compare, clans/scores/best, clans/activity, users/history) for each IP address."`
+	SearchTokenRequestsPerMinute int    `description:"Requests per minute allowed on the search methods of the API for each token."`
+	ReplayRequestsPerMinute      int    `description:"Requests per minute allowed on the replay downloads of the API (scores/replay, scores/replay/full) for each IP address."`
+	ReplayTokenRequestsPerMinute int    `description:"Requests per minute allowed on the replay downloads of the API for each token."`
+	ClanMaxMembers               int    `description:"Maximum number of members of a clan."`
+	ScoreSearchMaxRows           int    `description:"Maximum number of rows MySQL can expect to examine (according to EXPLAIN) for a score search."`
+	RedisAddr                    string
+	RedisPassword                string
+	RedisDB                      int
 }
 
+// Default rate limits of the API, used when they are not set in the
+// configuration file.
+const (
+	DefaultReadRequestsPerMinute        = 120
+	DefaultReadTokenRequestsPerMinute   = 600
+	DefaultWriteRequestsPerMinute       = 30
+	DefaultWriteTokenRequestsPerMinute  = 120
+	DefaultSearchRequestsPerMinute      = 20
+	DefaultSearchTokenRequestsPerMinute = 60
+	DefaultReplayRequestsPerMinute      = 10
+	DefaultReplayTokenRequestsPerMinute = 30
+)
+
+// DefaultClanMaxMembers is the maximum number of members of a clan, used when
+// it is not set in the configuration file.
+const DefaultClanMaxMembers = 16
+
 var cachedConf *Conf
 
 // Load creates a new Conf, using the data in the file "api.conf".
@@ -39,14 +68,26 @@ func Load() (c Conf, halt bool) {
 	halt = err == conf.ErrNoFile
 	if halt {
 		conf.MustExport(Conf{
-			DatabaseType:           "mysql",
-			DSN:                    "root@/ripple",
-			ListenTo:               ":40001",
-			Unix:                   false,
-			HanayoKey:              "Potato",
-			BeatmapRequestsPerUser: 2,
-			RankQueueSize:          25,
-			RedisAddr:              "localhost:6379",
+			DatabaseType:                 "mysql",
+			DSN:                          "root@/ripple",
+			ListenTo:                     ":40001",
+			Unix:                         false,
+			HanayoKey:                    "Potato",
+			BeatmapRequestsPerUser:       2,
+			RankQueueSize:                25,
+			PeppyRequestsPerMinute:       60,
+			ReplayFolder:                 "replays",
+			ReadRequestsPerMinute:        DefaultReadRequestsPerMinute,
+			ReadTokenRequestsPerMinute:   DefaultReadTokenRequestsPerMinute,
+			WriteRequestsPerMinute:       DefaultWriteRequestsPerMinute,
+			WriteTokenRequestsPerMinute:  DefaultWriteTokenRequestsPerMinute,
+			SearchRequestsPerMinute:      DefaultSearchRequestsPerMinute,
+			SearchTokenRequestsPerMinute: DefaultSearchTokenRequestsPerMinute,
+			ReplayRequestsPerMinute:      DefaultReplayRequestsPerMinute,
+			ReplayTokenRequestsPerMinute: DefaultReplayTokenRequestsPerMinute,
+			ClanMaxMembers:               DefaultClanMaxMembers,
+			ScoreSearchMaxRows:           1000000,
+			RedisAddr:                    "localhost:6379",
 		}, "api.conf")
 		fmt.Println("Please compile the configuration file (api.conf).")
 	}
@@ -0,0 +1,162 @@
+package common
+
+import (
+	"encoding/base64"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Keyset describes how a listing is sorted when using cursor-based (keyset)
+// pagination: rows are sorted by Column, and ties are broken by IDColumn.
+// Unlike Paginate, seeking through a keyset does not get slower as the
+// client goes further, nor does it skip or duplicate rows when new ones are
+// added between two requests.
+type Keyset struct {
+	Column    string
+	IDColumn  string
+	Ascending bool
+}
+
+// Seek returns an SQL condition selecting the rows which come after the
+// position encoded in cursor, along with its parameters. If the cursor is
+// empty (first page), the condition is simply "1". ok is false if the cursor
+// is not valid.
+func (k Keyset) Seek(cursor string) (where string, params []interface{}, ok bool) {
+	if cursor == "" {
+		return "1", nil, true
+	}
+	key, id, ok := decodeCursor(cursor)
+	if !ok {
+		return "", nil, false
+	}
+	op := "<"
+	if k.Ascending {
+		op = ">"
+	}
+	if k.Column == "" || k.Column == k.IDColumn {
+		return k.IDColumn + " " + op + " ?", []interface{}{id}, true
+	}
+	return fmt.Sprintf("(%[1]s %[3]s ? OR (%[1]s = ? AND %[2]s %[3]s ?))", k.Column, k.IDColumn, op),
+		[]interface{}{key, key, id}, true
+}
+
+// OrderBy returns the ORDER BY clause sorting the rows as the keyset expects.
+func (k Keyset) OrderBy() string {
+	sorting := "DESC"
+	if k.Ascending {
+		sorting = "ASC"
+	}
+	if k.Column == "" || k.Column == k.IDColumn {
+		return "ORDER BY " + k.IDColumn + " " + sorting
+	}
+	return "ORDER BY " + k.Column + " " + sorting + ", " + k.IDColumn + " " + sorting
+}
+
+// SortKeyset creates a Keyset for a listing which can be sorted as described
+// by config. The listing is sorted by the first valid sort parameter of the
+// request, or as in def if no valid sort parameter was passed.
+func SortKeyset(md MethodData, config SortConfiguration, def Keyset) Keyset {
+	if config.Table != "" {
+		config.Table += "."
+	}
+	for _, s := range md.Ctx.Request.URI().QueryArgs().PeekMulti("sort") {
+		sortParts := strings.Split(strings.ToLower(b2s(s)), ",")
+		if !contains(config.Allowed, sortParts[0]) {
+			continue
+		}
+		k := def
+		k.Column = config.Table + sortParts[0]
+		if len(sortParts) > 1 && contains([]string{"asc", "desc"}, sortParts[1]) {
+			k.Ascending = sortParts[1] == "asc"
+		} else {
+			k.Ascending = strings.ToUpper(config.DefaultSorting) == "ASC"
+		}
+		return k
+	}
+	return def
+}
+
+// Page is the pagination of a listing, either through Paginate or, if the
+// request passed the cursor parameter, through a Keyset.
+type Page struct {
+	// Where is the condition to be added to the WHERE clause of the query.
+	// It is always set, and defaults to "1".
+	Where   string
+	Params  []interface{}
+	OrderBy string
+	Limit   string
+	// Size is the maximum number of rows in a page. It is only set when using
+	// cursors.
+	Size int
+}
+
+// KeysetPaginate creates the pagination of a listing sorted as in k. If the
+// request does not have the cursor parameter, it falls back to Paginate with
+// the default ORDER BY clause orderBy. ok is false if the cursor passed is not
+// valid, in which case the request should be rejected rather than served the
+// first page.
+func KeysetPaginate(md MethodData, k Keyset, orderBy string, maxLimit int) (p Page, ok bool) {
+	if !md.HasQuery("cursor") {
+		return Page{
+			Where:   "1",
+			OrderBy: orderBy,
+			Limit:   Paginate(md.Query("p"), md.Query("l"), maxLimit),
+		}, true
+	}
+	where, params, ok := k.Seek(md.Query("cursor"))
+	if !ok {
+		return Page{}, false
+	}
+	size := PageLimit(md.Query("l"), maxLimit)
+	return Page{
+		Where:   where,
+		Params:  params,
+		OrderBy: k.OrderBy(),
+		Limit:   fmt.Sprintf(" LIMIT %d ", size),
+		Size:    size,
+	}, true
+}
+
+// Next returns the cursor to the next page, knowing the number of rows in
+// the current page and the sort key and ID of its last row. If there are no
+// more pages or the request is not using cursors, an empty string is returned.
+func (p Page) Next(rows int, key interface{}, id int) string {
+	if p.Size == 0 || rows < p.Size {
+		return ""
+	}
+	return EncodeCursor(key, id)
+}
+
+// EncodeCursor creates the opaque cursor pointing to the row having the
+// given sort key and ID.
+func EncodeCursor(key interface{}, id int) string {
+	var k string
+	switch key := key.(type) {
+	// Floats are formatted as float64 so that they compare as equal to the
+	// value in the database.
+	case float32:
+		k = strconv.FormatFloat(float64(key), 'g', -1, 64)
+	case float64:
+		k = strconv.FormatFloat(key, 'g', -1, 64)
+	default:
+		k = fmt.Sprint(key)
+	}
+	return base64.RawURLEncoding.EncodeToString([]byte(strconv.Itoa(id) + ":" + k))
+}
+
+func decodeCursor(cursor string) (key string, id int, ok bool) {
+	raw, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return "", 0, false
+	}
+	parts := strings.SplitN(string(raw), ":", 2)
+	if len(parts) != 2 {
+		return "", 0, false
+	}
+	id, err = strconv.Atoi(parts[0])
+	if err != nil {
+		return "", 0, false
+	}
+	return parts[1], id, true
+}
@@ -0,0 +1,113 @@
+package common
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestKeyset_Seek(t *testing.T) {
+	tests := []struct {
+		name       string
+		keyset     Keyset
+		cursor     string
+		wantWhere  string
+		wantParams []interface{}
+		wantOK     bool
+	}{
+		{
+			"first page",
+			Keyset{Column: "s.pp", IDColumn: "s.id"},
+			"",
+			"1",
+			nil,
+			true,
+		},
+		{
+			"invalid",
+			Keyset{Column: "s.pp", IDColumn: "s.id"},
+			"!!",
+			"",
+			nil,
+			false,
+		},
+		{
+			"no id",
+			Keyset{Column: "s.pp", IDColumn: "s.id"},
+			"MTIz",
+			"",
+			nil,
+			false,
+		},
+		{
+			"column",
+			Keyset{Column: "s.pp", IDColumn: "s.id"},
+			EncodeCursor(float32(123.5), 42),
+			"(s.pp < ? OR (s.pp = ? AND s.id < ?))",
+			[]interface{}{"123.5", "123.5", 42},
+			true,
+		},
+		{
+			"id only",
+			Keyset{IDColumn: "users.id", Ascending: true},
+			EncodeCursor(1000, 1000),
+			"users.id > ?",
+			[]interface{}{1000},
+			true,
+		},
+		{
+			"key with colon",
+			Keyset{Column: "users.username", IDColumn: "users.id", Ascending: true},
+			EncodeCursor("a:b", 3),
+			"(users.username > ? OR (users.username = ? AND users.id > ?))",
+			[]interface{}{"a:b", "a:b", 3},
+			true,
+		},
+	}
+	for _, tt := range tests {
+		where, params, ok := tt.keyset.Seek(tt.cursor)
+		if ok != tt.wantOK {
+			t.Errorf("%q. Keyset.Seek() ok = %v, want %v", tt.name, ok, tt.wantOK)
+		}
+		if where != tt.wantWhere {
+			t.Errorf("%q. Keyset.Seek() where = %v, want %v", tt.name, where, tt.wantWhere)
+		}
+		if !reflect.DeepEqual(params, tt.wantParams) {
+			t.Errorf("%q. Keyset.Seek() params = %v, want %v", tt.name, params, tt.wantParams)
+		}
+	}
+}
+
+func TestKeyset_OrderBy(t *testing.T) {
+	tests := []struct {
+		name   string
+		keyset Keyset
+		want   string
+	}{
+		{"column", Keyset{Column: "s.pp", IDColumn: "s.id"}, "ORDER BY s.pp DESC, s.id DESC"},
+		{"id only", Keyset{IDColumn: "s.id", Ascending: true}, "ORDER BY s.id ASC"},
+		{"same column", Keyset{Column: "s.id", IDColumn: "s.id"}, "ORDER BY s.id DESC"},
+	}
+	for _, tt := range tests {
+		if got := tt.keyset.OrderBy(); got != tt.want {
+			t.Errorf("%q. Keyset.OrderBy() = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestPage_Next(t *testing.T) {
+	tests := []struct {
+		name string
+		page Page
+		rows int
+		want string
+	}{
+		{"not using cursors", Page{}, 50, ""},
+		{"last page", Page{Size: 50}, 49, ""},
+		{"full page", Page{Size: 50}, 50, EncodeCursor(7, 7)},
+	}
+	for _, tt := range tests {
+		if got := tt.page.Next(tt.rows, 7, 7); got != tt.want {
+			t.Errorf("%q. Page.Next() = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
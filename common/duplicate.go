@@ -0,0 +1,32 @@
+package common
+
+import (
+	"strings"
+
+	"github.com/go-sql-driver/mysql"
+)
+
+// erDupEntry is the number of the MySQL error returned when a query violates
+// an unique key.
+const erDupEntry = 1062
+
+// DuplicateKey returns the name of the unique key violated by a query, if err
+// is a duplicate entry error. The name is without the table, which is added in
+// front of it by MySQL 8.
+func DuplicateKey(err error) (key string, ok bool) {
+	me, ok := err.(*mysql.MySQLError)
+	if !ok || me.Number != erDupEntry {
+		return "", false
+	}
+	// Duplicate entry 'x' for key 'clans.name'
+	const forKey = " for key '"
+	i := strings.LastIndex(me.Message, forKey)
+	if i == -1 {
+		return "", true
+	}
+	key = strings.TrimSuffix(me.Message[i+len(forKey):], "'")
+	if i := strings.LastIndexByte(key, '.'); i != -1 {
+		key = key[i+1:]
+	}
+	return key, true
+}
@@ -0,0 +1,30 @@
+package common
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/go-sql-driver/mysql"
+)
+
+func TestDuplicateKey(t *testing.T) {
+	tests := []struct {
+		name    string
+		err     error
+		wantKey string
+		wantOK  bool
+	}{
+		{"nil", nil, "", false},
+		{"other error", errors.New("Duplicate entry 'a' for key 'name'"), "", false},
+		{"other mysql error", &mysql.MySQLError{Number: 1146, Message: "Table 'ripple.clans' doesn't exist"}, "", false},
+		{"mysql 5", &mysql.MySQLError{Number: 1062, Message: "Duplicate entry 'abc' for key 'tag'"}, "tag", true},
+		{"mysql 8", &mysql.MySQLError{Number: 1062, Message: "Duplicate entry 'a.b' for key 'clans.name'"}, "name", true},
+		{"no key", &mysql.MySQLError{Number: 1062, Message: "Duplicate entry"}, "", true},
+	}
+	for _, tt := range tests {
+		key, ok := DuplicateKey(tt.err)
+		if key != tt.wantKey || ok != tt.wantOK {
+			t.Errorf("%q: DuplicateKey() = %q, %v, want %q, %v", tt.name, key, ok, tt.wantKey, tt.wantOK)
+		}
+	}
+}
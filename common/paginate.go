@@ -6,17 +6,24 @@ import "fmt"
 func Paginate(page, limit string, maxLimit int) string {
 	var (
 		p = Int(page)
-		l = Int(limit)
+		l = PageLimit(limit, maxLimit)
 	)
 	if p < 1 {
 		p = 1
 	}
+	start := uint(p-1) * uint(l)
+	return fmt.Sprintf(" LIMIT %d,%d ", start, l)
+}
+
+// PageLimit returns the number of elements to be shown in a page, which is
+// 50 by default and can't be higher than maxLimit.
+func PageLimit(limit string, maxLimit int) int {
+	l := Int(limit)
 	if l < 1 {
 		l = 50
 	}
 	if l > maxLimit {
 		l = maxLimit
 	}
-	start := uint(p-1) * uint(l)
-	return fmt.Sprintf(" LIMIT %d,%d ", start, l)
+	return l
 }
@@ -0,0 +1,48 @@
+package common
+
+import (
+	"path/filepath"
+	"strconv"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"gopkg.in/redis.v5"
+)
+
+// ReplayPath returns the path to the file containing the replay data of a
+// score, as saved by the score server.
+func ReplayPath(scoreID int) string {
+	folder := "replays"
+	if c := GetConf(); c != nil && c.ReplayFolder != "" {
+		folder = c.ReplayFolder
+	}
+	return filepath.Join(folder, "replay_"+strconv.Itoa(scoreID)+".osr")
+}
+
+var replayModes = [...]string{"std", "taiko", "ctb", "mania"}
+
+// ReplayWatched increments the number of times the replays of an user in the
+// given mode and special mode have been watched. Nothing is counted for the
+// special modes which don't exist.
+func ReplayWatched(db *sqlx.DB, userID, mode, smode int) error {
+	s, ok := GetSpecialMode(smode)
+	if !ok {
+		return nil
+	}
+	if mode < 0 || mode > 3 {
+		mode = 0
+	}
+	m := replayModes[mode]
+	_, err := db.Exec("UPDATE "+s.StatsTable+" SET replays_watched_"+m+" = replays_watched_"+m+" + 1 WHERE id = ?", userID)
+	return err
+}
+
+// replayViewExpiration is how long a download of a replay is remembered, so
+// that downloading it again from the same IP address is not counted again.
+const replayViewExpiration = time.Hour
+
+// NewReplayView tells whether the replay of a score has not been downloaded
+// from an IP address in the last hour, and remembers that it has been now.
+func NewReplayView(r *redis.Client, scoreID int, ip string) (bool, error) {
+	return r.SetNX("api:replay_views:"+strconv.Itoa(scoreID)+":"+ip, 1, replayViewExpiration).Result()
+}
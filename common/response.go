@@ -34,3 +34,14 @@ func SimpleResponse(code int, message string) CodeMessager {
 		Message: message,
 	}
 }
+
+// RawResponse is a response which is sent to the client as it is, instead of
+// being encoded in JSON, such as a file to download.
+type RawResponse struct {
+	ResponseBase
+	ContentType string
+	// If Filename is set, the client is asked to download the response as
+	// a file with that name.
+	Filename string
+	Body     []byte
+}
@@ -0,0 +1,82 @@
+package common
+
+import (
+	"strconv"
+
+	"github.com/valyala/fasthttp"
+)
+
+// SpecialMode is a way of playing the game modes, such as relax, which has
+// its own stats and leaderboards. The scores of all the special modes are in
+// scores_master, told apart by their special_mode.
+type SpecialMode struct {
+	// ID is the special_mode of the scores.
+	ID   int
+	Name string
+	// StatsTable is the table with the stats of the users, which has the
+	// same stats columns as users_stats.
+	StatsTable string
+	// KeySuffix is added to the names of the redis keys of the special mode,
+	// e.g. ripple:leaderboard_relax.
+	KeySuffix string
+}
+
+// The special modes.
+var (
+	Vanilla   = SpecialMode{ID: 0, Name: "vanilla", StatsTable: "users_stats"}
+	Relax     = SpecialMode{ID: 1, Name: "relax", StatsTable: "rx_stats", KeySuffix: "_relax"}
+	Autopilot = SpecialMode{ID: 2, Name: "autopilot", StatsTable: "ap_stats", KeySuffix: "_autopilot"}
+)
+
+// SpecialModes are all the special modes, with their ID as index.
+var SpecialModes = [...]SpecialMode{Vanilla, Relax, Autopilot}
+
+// GetSpecialMode returns the special mode with the given ID, and whether there
+// is one.
+func GetSpecialMode(id int) (SpecialMode, bool) {
+	if id < 0 || id >= len(SpecialModes) {
+		return Vanilla, false
+	}
+	return SpecialModes[id], true
+}
+
+// SpecialModeFromQuery returns the special mode requested in a query string:
+// its ID can be passed in smode, or rx=1 and ap=1 can be used as shorthands for
+// relax and autopilot. If there is no such special mode, it is vanilla.
+func SpecialModeFromQuery(args *fasthttp.Args) SpecialMode {
+	if !args.Has("smode") {
+		switch {
+		case Int(string(args.Peek("rx"))) > 0:
+			return Relax
+		case Int(string(args.Peek("ap"))) > 0:
+			return Autopilot
+		}
+	}
+	s, _ := GetSpecialMode(Int(string(args.Peek("smode"))))
+	return s
+}
+
+// SpecialMode returns the special mode requested, using SpecialModeFromQuery.
+func (md MethodData) SpecialMode() SpecialMode {
+	return SpecialModeFromQuery(md.Ctx.QueryArgs())
+}
+
+// Key returns the name of the redis key called name in the special mode,
+// followed by a colon, e.g. "ripple:leaderboard_relax:" for "leaderboard".
+func (s SpecialMode) Key(name string) string {
+	return "ripple:" + name + s.KeySuffix + ":"
+}
+
+// LeaderboardKey returns the prefix of the keys of the leaderboards of the
+// special mode, to which the mode is appended, and then optionally the
+// country.
+func (s SpecialMode) LeaderboardKey() string {
+	return s.Key("leaderboard")
+}
+
+// ScoresFilter returns the condition selecting the scores of the special mode
+// from scores_master, aliased as table.
+func (s SpecialMode) ScoresFilter(table string) string {
+	// It's safe to build the condition directly, because ID is an int.
+	return table + ".special_mode = " + strconv.Itoa(s.ID)
+}
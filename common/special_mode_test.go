@@ -0,0 +1,54 @@
+package common
+
+import (
+	"testing"
+
+	"github.com/valyala/fasthttp"
+)
+
+func TestSpecialModeFromQuery(t *testing.T) {
+	tests := []struct {
+		query string
+		want  SpecialMode
+	}{
+		{"", Vanilla},
+		{"smode=0", Vanilla},
+		{"smode=1", Relax},
+		{"smode=-1", Vanilla},
+		{"smode=2", Autopilot},
+		{"smode=99", Vanilla},
+		{"smode=abc", Vanilla},
+		{"rx=1", Relax},
+		{"rx=0", Vanilla},
+		{"smode=0&rx=1", Vanilla},
+		{"ap=1", Autopilot},
+		{"smode=1&ap=1", Relax},
+	}
+	for _, tt := range tests {
+		var args fasthttp.Args
+		args.Parse(tt.query)
+		if got := SpecialModeFromQuery(&args); got != tt.want {
+			t.Errorf("%q: SpecialModeFromQuery() = %v, want %v", tt.query, got.Name, tt.want.Name)
+		}
+	}
+}
+
+func TestSpecialMode_Keys(t *testing.T) {
+	tests := []struct {
+		s           SpecialMode
+		leaderboard string
+		filter      string
+	}{
+		{Vanilla, "ripple:leaderboard:", "s.special_mode = 0"},
+		{Relax, "ripple:leaderboard_relax:", "s.special_mode = 1"},
+		{Autopilot, "ripple:leaderboard_autopilot:", "s.special_mode = 2"},
+	}
+	for _, tt := range tests {
+		if got := tt.s.LeaderboardKey(); got != tt.leaderboard {
+			t.Errorf("%s: LeaderboardKey() = %q, want %q", tt.s.Name, got, tt.leaderboard)
+		}
+		if got := tt.s.ScoresFilter("s"); got != tt.filter {
+			t.Errorf("%s: ScoresFilter() = %q, want %q", tt.s.Name, got, tt.filter)
+		}
+	}
+}
@@ -22,6 +22,15 @@ func (w *WhereClause) Where(clause, passedParam string, allowedValues ...string)
 	return w
 }
 
+// Raw adds a new clause to the WhereClause, with any number of parameters of
+// any type. Unlike Where, the clause is always added.
+func (w *WhereClause) Raw(clause string, params ...interface{}) *WhereClause {
+	w.addWhere()
+	w.Clause += clause
+	w.Params = append(w.Params, params...)
+	return w
+}
+
 func (w *WhereClause) addWhere() {
 	// if string is empty add "WHERE", else add AND
 	if w.Clause == "" {
@@ -29,7 +29,7 @@ func Test_generateQuestionMarks(t *testing.T) {
 func TestWhereClause_In(t *testing.T) {
 	type args struct {
 		initial string
-		fields  []string
+		fields  [][]byte
 	}
 	tests := []struct {
 		name   string
@@ -40,13 +40,13 @@ func TestWhereClause_In(t *testing.T) {
 		{
 			"simple",
 			&WhereClause{},
-			args{"users.id", []string{"1", "2", "3"}},
+			args{"users.id", [][]byte{[]byte("1"), []byte("2"), []byte("3")}},
 			&WhereClause{"WHERE users.id IN (?, ?, ?)", []interface{}{"1", "2", "3"}, false},
 		},
 		{
 			"withExisting",
 			Where("users.username = ?", "Howl").Where("users.xd > ?", "6"),
-			args{"users.id", []string{"1"}},
+			args{"users.id", [][]byte{[]byte("1")}},
 			&WhereClause{
 				"WHERE users.username = ? AND users.xd > ? AND users.id IN (?)",
 				[]interface{}{"Howl", "6", "1"},
@@ -95,3 +95,39 @@ func TestWhere(t *testing.T) {
 		}
 	}
 }
+
+func TestWhereClause_Raw(t *testing.T) {
+	type args struct {
+		clause string
+		params []interface{}
+	}
+	tests := []struct {
+		name   string
+		fields *WhereClause
+		args   args
+		want   *WhereClause
+	}{
+		{
+			"noParams",
+			&WhereClause{},
+			args{"s.completed = '3'", nil},
+			&WhereClause{"WHERE s.completed = '3'", nil, false},
+		},
+		{
+			"manyParams",
+			Where("users.username = ?", "Howl"),
+			args{"(s.mods & ?) = ?", []interface{}{72, 72}},
+			&WhereClause{
+				"WHERE users.username = ? AND (s.mods & ?) = ?",
+				[]interface{}{"Howl", 72, 72},
+				false,
+			},
+		},
+	}
+	for _, tt := range tests {
+		w := tt.fields
+		if got := w.Raw(tt.args.clause, tt.args.params...); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%q. WhereClause.Raw() = %#v, want %#v", tt.name, got, tt.want)
+		}
+	}
+}
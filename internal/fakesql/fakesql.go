@@ -0,0 +1,108 @@
+// Package fakesql provides a database for the tests which doesn't need a
+// server. It can't tell whether the queries are right, only what they are, so
+// it is used to test how the methods build their queries and what they do
+// with the rows they get back.
+package fakesql
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"io"
+	"strings"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// Result is the answer of the database to the queries containing Match.
+type Result struct {
+	Match   string
+	Columns []string
+	Rows    [][]driver.Value
+}
+
+// Query is a query, or a statement, run on the database.
+type Query struct {
+	Query string
+	Args  []driver.Value
+}
+
+// Open returns a database answering each query with the first of results
+// that matches it, or with no rows. The statements are all successful and
+// affect one row. The queries and the statements are recorded in the returned
+// slice, in the order they are run.
+func Open(results ...Result) (*sqlx.DB, *[]Query) {
+	queries := &[]Query{}
+	db := sql.OpenDB(connector{results, queries})
+	return sqlx.NewDb(db, "mysql"), queries
+}
+
+type connector struct {
+	results []Result
+	queries *[]Query
+}
+
+func (c connector) Connect(context.Context) (driver.Conn, error) { return conn(c), nil }
+func (c connector) Driver() driver.Driver                        { return fakeDriver{} }
+
+type fakeDriver struct{}
+
+func (fakeDriver) Open(string) (driver.Conn, error) {
+	return nil, errors.New("fakesql: use fakesql.Open")
+}
+
+type conn connector
+
+func (c conn) Prepare(query string) (driver.Stmt, error) { return stmt{c, query}, nil }
+func (c conn) Close() error                              { return nil }
+func (c conn) Begin() (driver.Tx, error)                 { return tx{}, nil }
+
+type tx struct{}
+
+func (tx) Commit() error   { return nil }
+func (tx) Rollback() error { return nil }
+
+type stmt struct {
+	conn  conn
+	query string
+}
+
+func (s stmt) Close() error  { return nil }
+func (s stmt) NumInput() int { return -1 }
+
+func (s stmt) Exec(args []driver.Value) (driver.Result, error) {
+	*s.conn.queries = append(*s.conn.queries, Query{s.query, args})
+	return result{}, nil
+}
+
+func (s stmt) Query(args []driver.Value) (driver.Rows, error) {
+	*s.conn.queries = append(*s.conn.queries, Query{s.query, args})
+	for _, r := range s.conn.results {
+		if strings.Contains(s.query, r.Match) {
+			return &rows{columns: r.Columns, rows: r.Rows}, nil
+		}
+	}
+	return &rows{}, nil
+}
+
+type result struct{}
+
+func (result) LastInsertId() (int64, error) { return 1, nil }
+func (result) RowsAffected() (int64, error) { return 1, nil }
+
+type rows struct {
+	columns []string
+	rows    [][]driver.Value
+}
+
+func (r *rows) Columns() []string { return r.columns }
+func (r *rows) Close() error      { return nil }
+func (r *rows) Next(dest []driver.Value) error {
+	if len(r.rows) == 0 {
+		return io.EOF
+	}
+	copy(dest, r.rows[0])
+	r.rows = r.rows[1:]
+	return nil
+}
@@ -6,22 +6,54 @@ import (
 	"time"
 )
 
+// Limiter limits the number of requests that can be made for a certain key
+// (such as an user or an IP address) in a minute.
+type Limiter interface {
+	// Request blocks until a request can be made.
+	Request(u string, perMinute int)
+	// NonBlockingRequest returns whether a request can be made, and if it
+	// can, counts it as done.
+	NonBlockingRequest(u string, perMinute int) bool
+	// Take is like NonBlockingRequest, but it also returns the state of the
+	// limit, so that it can be shown to the client.
+	Take(u string, perMinute int) Result
+}
+
+// Result is the outcome of a Take.
+type Result struct {
+	Allowed bool
+	// Limit is the number of requests that can be made in a minute.
+	Limit int
+	// Remaining is the number of requests that can still be made right away.
+	Remaining int
+	// RetryAfter is how long to wait before the next request is allowed, if
+	// this one wasn't.
+	RetryAfter time.Duration
+	// ResetAfter is how long it takes for Remaining to go back to Limit.
+	ResetAfter time.Duration
+}
+
 // Request is a Request with DefaultLimiter.
 func Request(u string, perMinute int) { DefaultLimiter.Request(u, perMinute) }
 
+// Take is a Take with DefaultLimiter.
+func Take(u string, perMinute int) Result { return DefaultLimiter.Take(u, perMinute) }
+
 // NonBlockingRequest is a NonBlockingRequest with DefaultLimiter.
 func NonBlockingRequest(u string, perMinute int) bool {
 	return DefaultLimiter.NonBlockingRequest(u, perMinute)
 }
 
-// DefaultLimiter is the RateLimiter used by the package-level
-// Request and NonBlockingRequest.
-var DefaultLimiter = &RateLimiter{
+// DefaultLimiter is the Limiter used by the package-level Request and
+// NonBlockingRequest. It is an in-memory RateLimiter, unless it is replaced
+// with a RedisLimiter.
+var DefaultLimiter Limiter = &RateLimiter{
 	Map:   make(map[string]chan struct{}),
 	Mutex: &sync.RWMutex{},
 }
 
-// RateLimiter is a simple rate limiter.
+// RateLimiter is a simple in-memory rate limiter. Its limits are not shared
+// between processes, and are lost when the process is restarted.
 type RateLimiter struct {
 	Map   map[string]chan struct{}
 	Mutex *sync.RWMutex
@@ -38,6 +70,29 @@ func (s *RateLimiter) NonBlockingRequest(u string, perMinute int) bool {
 	return s.request(u, perMinute, false)
 }
 
+// Take is like NonBlockingRequest, but it also returns the state of the limit.
+func (s *RateLimiter) Take(u string, perMinute int) Result {
+	if perMinute < 1 {
+		perMinute = 1
+	}
+	s.check()
+	allowed := s.request(u, perMinute, false)
+	s.Mutex.RLock()
+	remaining := len(s.Map[u])
+	s.Mutex.RUnlock()
+	interval := time.Minute / time.Duration(perMinute)
+	res := Result{
+		Allowed:    allowed,
+		Limit:      perMinute,
+		Remaining:  remaining,
+		ResetAfter: time.Duration(perMinute-remaining) * interval,
+	}
+	if !allowed {
+		res.RetryAfter = interval
+	}
+	return res
+}
+
 func (s *RateLimiter) request(u string, perMinute int, blocking bool) bool {
 	s.check()
 	s.Mutex.RLock()
@@ -0,0 +1,139 @@
+package limit
+
+import (
+	"errors"
+	"time"
+
+	"gopkg.in/redis.v5"
+)
+
+// gcra implements the generic cell rate algorithm. The key holds the
+// theoretical arrival time (TAT) of the next request, in microseconds.
+//
+// ARGV[1] is the emission interval and ARGV[2] the burst tolerance, both in
+// microseconds. The current time is taken from Redis, so that the processes
+// sharing a limit don't need to have their clocks in sync. As TIME is not
+// deterministic, redis.replicate_commands has the script replicated by its
+// effects, which Redis before 5 doesn't do by default.
+// It returns whether the request is allowed, the number of requests that can
+// still be made right away, the time after which the request can be retried
+// and the time after which the limit is reset.
+var gcra = redis.NewScript(`
+redis.replicate_commands()
+local emission = tonumber(ARGV[1])
+local tolerance = tonumber(ARGV[2])
+local time = redis.call("TIME")
+local now = tonumber(time[1]) * 1000000 + tonumber(time[2])
+local tat = tonumber(redis.call("GET", KEYS[1]) or now)
+if tat < now then
+	tat = now
+end
+local newTat = tat + emission
+local allowAt = newTat - tolerance
+if allowAt > now then
+	return {0, 0, allowAt - now, tat - now}
+end
+redis.call("SET", KEYS[1], newTat, "PX", math.ceil((newTat - now) / 1000))
+return {1, math.floor((now - allowAt) / emission), 0, newTat - now}
+`)
+
+var errUnexpectedReply = errors.New("limit: unexpected reply from redis")
+
+// RedisLimiter is a Limiter storing its state in Redis, so that limits are
+// shared among all the processes using the same Redis and survive restarts.
+// Up to perMinute requests can be made at once, and then one more every
+// minute/perMinute.
+type RedisLimiter struct {
+	Client *redis.Client
+	// Prefix is prepended to the keys passed to the limiter to create the
+	// Redis keys. Defaults to "api:ratelimit:".
+	Prefix string
+	// Fallback is used when Redis can't be reached. If nil, requests are
+	// always allowed when Redis is down.
+	Fallback Limiter
+}
+
+// NewRedisLimiter creates a RedisLimiter using the given client, falling back
+// to an in-memory RateLimiter.
+func NewRedisLimiter(c *redis.Client) *RedisLimiter {
+	return &RedisLimiter{
+		Client:   c,
+		Fallback: &RateLimiter{},
+	}
+}
+
+// Request blocks until a request can be made.
+func (r *RedisLimiter) Request(u string, perMinute int) {
+	for {
+		res, err := r.take(u, perMinute)
+		if err != nil {
+			if r.Fallback != nil {
+				r.Fallback.Request(u, perMinute)
+			}
+			return
+		}
+		if res.Allowed {
+			return
+		}
+		time.Sleep(res.RetryAfter)
+	}
+}
+
+// NonBlockingRequest checks if it can do a request. If it can't, it returns
+// false, else it returns true if the request succeded.
+func (r *RedisLimiter) NonBlockingRequest(u string, perMinute int) bool {
+	res, err := r.take(u, perMinute)
+	if err != nil {
+		if r.Fallback != nil {
+			return r.Fallback.NonBlockingRequest(u, perMinute)
+		}
+		return true
+	}
+	return res.Allowed
+}
+
+// Take is like NonBlockingRequest, but it also returns the state of the limit.
+func (r *RedisLimiter) Take(u string, perMinute int) Result {
+	res, err := r.take(u, perMinute)
+	if err != nil {
+		if r.Fallback != nil {
+			return r.Fallback.Take(u, perMinute)
+		}
+		return Result{Allowed: true, Limit: perMinute, Remaining: perMinute}
+	}
+	return res
+}
+
+func (r *RedisLimiter) take(u string, perMinute int) (Result, error) {
+	if perMinute < 1 {
+		perMinute = 1
+	}
+	prefix := r.Prefix
+	if prefix == "" {
+		prefix = "api:ratelimit:"
+	}
+	emission := int64(time.Minute/time.Microsecond) / int64(perMinute)
+	res, err := gcra.Run(r.Client, []string{prefix + u},
+		emission, emission*int64(perMinute)).Result()
+	if err != nil {
+		return Result{}, err
+	}
+	vals, ok := res.([]interface{})
+	if !ok || len(vals) != 4 {
+		return Result{}, errUnexpectedReply
+	}
+	ints := make([]int64, len(vals))
+	for i, v := range vals {
+		ints[i], ok = v.(int64)
+		if !ok {
+			return Result{}, errUnexpectedReply
+		}
+	}
+	return Result{
+		Allowed:    ints[0] == 1,
+		Limit:      perMinute,
+		Remaining:  int(ints[1]),
+		RetryAfter: time.Duration(ints[2]) * time.Microsecond,
+		ResetAfter: time.Duration(ints[3]) * time.Microsecond,
+	}, nil
+}
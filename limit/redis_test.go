@@ -0,0 +1,166 @@
+package limit
+
+import (
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"gopkg.in/redis.v5"
+)
+
+// testRedis starts an in-memory Redis server for the test, and returns a
+// limiter using it.
+func testRedis(t *testing.T) *RedisLimiter {
+	l, _ := testRedisServer(t)
+	return l
+}
+
+// testRedisServer is like testRedis, but it also returns the server, whose
+// clock can be set.
+func testRedisServer(t *testing.T) (*RedisLimiter, *miniredis.Miniredis) {
+	s, err := miniredis.Run()
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(s.Close)
+	c := redis.NewClient(&redis.Options{Addr: s.Addr()})
+	t.Cleanup(func() { c.Close() })
+	return NewRedisLimiter(c), s
+}
+
+func TestRedisLimiterNonBlockingRequest(t *testing.T) {
+	l := testRedis(t)
+	tests := []struct {
+		name      string
+		key       string
+		perMinute int
+		allowed   int
+	}{
+		{"one", "a", 1, 1},
+		{"burst", "b", 10, 10},
+		{"other key", "c", 3, 3},
+	}
+	for _, tt := range tests {
+		allowed := 0
+		for i := 0; i < tt.perMinute+5; i++ {
+			if l.NonBlockingRequest(tt.key, tt.perMinute) {
+				allowed++
+			}
+		}
+		if allowed != tt.allowed {
+			t.Errorf("%q. NonBlockingRequest() allowed %v requests, want %v", tt.name, allowed, tt.allowed)
+		}
+	}
+}
+
+func TestRedisLimiterShared(t *testing.T) {
+	a := testRedis(t)
+	b := NewRedisLimiter(a.Client)
+	b.Prefix = a.Prefix
+	for i := 0; i < 4; i++ {
+		l := a
+		if i%2 == 1 {
+			l = b
+		}
+		if !l.NonBlockingRequest("u", 4) {
+			t.Fatalf("request %d denied, want allowed", i)
+		}
+	}
+	if a.NonBlockingRequest("u", 4) || b.NonBlockingRequest("u", 4) {
+		t.Error("request allowed after the limit was shared, want denied")
+	}
+}
+
+func TestRedisLimiterTake(t *testing.T) {
+	l := testRedis(t)
+	res, err := l.take("u", 60)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !res.Allowed || res.Remaining != 59 {
+		t.Errorf("take() = %+v, want allowed with 59 remaining", res)
+	}
+	for i := 0; i < 59; i++ {
+		l.take("u", 60)
+	}
+	res, err = l.take("u", 60)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.Allowed || res.RetryAfter <= 0 || res.RetryAfter > time.Second {
+		t.Errorf("take() = %+v, want denied with retry after at most 1s", res)
+	}
+}
+
+func TestRedisLimiterReset(t *testing.T) {
+	l, s := testRedisServer(t)
+	now := time.Unix(1500000000, 0)
+	tests := []struct {
+		name  string
+		after time.Duration
+		want  Result
+	}{
+		{"first", 0, Result{Allowed: true, Limit: 2, Remaining: 1, ResetAfter: 30 * time.Second}},
+		{"burst", 0, Result{Allowed: true, Limit: 2, Remaining: 0, ResetAfter: time.Minute}},
+		{"denied", 0, Result{Limit: 2, RetryAfter: 30 * time.Second, ResetAfter: time.Minute}},
+		{"still denied", 29 * time.Second, Result{Limit: 2, RetryAfter: time.Second, ResetAfter: 31 * time.Second}},
+		{"one more", 30 * time.Second, Result{Allowed: true, Limit: 2, Remaining: 0, ResetAfter: time.Minute}},
+		{"reset", 120 * time.Second, Result{Allowed: true, Limit: 2, Remaining: 1, ResetAfter: 30 * time.Second}},
+	}
+	for _, tt := range tests {
+		s.SetTime(now.Add(tt.after))
+		got, err := l.take("u", 2)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got != tt.want {
+			t.Errorf("%q. take() = %+v, want %+v", tt.name, got, tt.want)
+		}
+	}
+	ttl, err := l.Client.PTTL("api:ratelimit:u").Result()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ttl <= 0 || ttl > 30*time.Second {
+		t.Errorf("the key expires in %v, want at most 30s", ttl)
+	}
+}
+
+// countLimiter is a Limiter counting the requests made to it.
+type countLimiter struct{ requests int }
+
+func (c *countLimiter) Request(u string, perMinute int) { c.requests++ }
+func (c *countLimiter) NonBlockingRequest(u string, perMinute int) bool {
+	c.requests++
+	return false
+}
+func (c *countLimiter) Take(u string, perMinute int) Result {
+	c.requests++
+	return Result{Limit: perMinute}
+}
+
+func TestRedisLimiterFallback(t *testing.T) {
+	fallback := &countLimiter{}
+	l := &RedisLimiter{
+		Client: redis.NewClient(&redis.Options{
+			Addr:        "127.0.0.1:1",
+			DialTimeout: 100 * time.Millisecond,
+		}),
+		Fallback: fallback,
+	}
+	if l.NonBlockingRequest("u", 1) {
+		t.Error("NonBlockingRequest() = true, want the result of the fallback")
+	}
+	l.Request("u", 1)
+	if res := l.Take("u", 1); res.Allowed {
+		t.Error("Take().Allowed = true, want the result of the fallback")
+	}
+	if fallback.requests != 3 {
+		t.Errorf("fallback received %v requests, want 3", fallback.requests)
+	}
+
+	l.Fallback = nil
+	if !l.NonBlockingRequest("u", 1) {
+		t.Error("NonBlockingRequest() without a fallback = false, want true")
+	}
+}
@@ -0,0 +1,89 @@
+// Package osr creates osu! replay files (.osr).
+package osr
+
+import (
+	"bytes"
+	"crypto/md5"
+	"fmt"
+	"io"
+	"time"
+)
+
+// Version is the game version written in the replays.
+const Version = 20170503
+
+// Replay is an osu! replay, as stored in an .osr file.
+type Replay struct {
+	Mode       byte
+	Version    int32
+	BeatmapMD5 string
+	Username   string
+	ReplayMD5  string
+	Count300   uint16
+	Count100   uint16
+	Count50    uint16
+	CountGeki  uint16
+	CountKatu  uint16
+	CountMiss  uint16
+	Score      int32
+	MaxCombo   uint16
+	Perfect    bool
+	Mods       int32
+	LifeBar    string
+	Time       time.Time
+	// Data is the LZMA-compressed replay data, which is what the score server
+	// stores as the replay.
+	Data    []byte
+	ScoreID int64
+}
+
+// ticksEpoch is the number of .NET ticks (100 nanoseconds) between
+// 0001-01-01 and the unix epoch.
+const ticksEpoch = 621355968000000000
+
+// WriteTo writes the replay to w.
+func (r Replay) WriteTo(w io.Writer) (int64, error) {
+	ow := NewWriter(w)
+	ow.Byte(r.Mode)
+	ow.Int32(r.Version)
+	ow.String(r.BeatmapMD5)
+	ow.String(r.Username)
+	ow.String(r.ReplayMD5)
+	ow.Uint16(r.Count300)
+	ow.Uint16(r.Count100)
+	ow.Uint16(r.Count50)
+	ow.Uint16(r.CountGeki)
+	ow.Uint16(r.CountKatu)
+	ow.Uint16(r.CountMiss)
+	ow.Int32(r.Score)
+	ow.Uint16(r.MaxCombo)
+	ow.Bool(r.Perfect)
+	ow.Int32(r.Mods)
+	ow.String(r.LifeBar)
+	ow.Int64(r.Time.UnixNano()/100 + ticksEpoch)
+	ow.Int32(int32(len(r.Data)))
+	ow.Write(r.Data)
+	ow.Int64(r.ScoreID)
+	return ow.N(), ow.Err()
+}
+
+// MarshalBinary encodes the replay in the .osr format.
+func (r Replay) MarshalBinary() ([]byte, error) {
+	var b bytes.Buffer
+	_, err := r.WriteTo(&b)
+	return b.Bytes(), err
+}
+
+// Hash computes the hash of the replay in the same way as the score server,
+// knowing the rank (e.g. "SH") of the score.
+func (r Replay) Hash(rank string) string {
+	perfect := "False"
+	if r.Perfect {
+		perfect = "True"
+	}
+	return fmt.Sprintf("%x", md5.Sum([]byte(fmt.Sprintf(
+		"%dp%do%do%dt%da%sr%de%sy%so%du%s%dTrue",
+		int(r.Count100)+int(r.Count300), r.Count50, r.CountGeki, r.CountKatu, r.CountMiss,
+		r.BeatmapMD5, r.MaxCombo, perfect, r.Username, r.Score, rank, r.Mods,
+	))))
+}
@@ -0,0 +1,65 @@
+package osr
+
+import (
+	"bytes"
+	"testing"
+	"time"
+)
+
+func TestReplay_MarshalBinary(t *testing.T) {
+	r := Replay{
+		Mode:       1,
+		Version:    Version,
+		BeatmapMD5: "ab",
+		Username:   "c",
+		Count300:   300,
+		Score:      1000000,
+		Perfect:    true,
+		Mods:       8,
+		Time:       time.Unix(0, 0),
+		Data:       []byte{0xde, 0xad},
+		ScoreID:    42,
+	}
+	want := []byte{
+		0x01,                   // mode
+		0x07, 0xc7, 0x33, 0x01, // version
+		0x0b, 0x02, 'a', 'b', // beatmap md5
+		0x0b, 0x01, 'c', // username
+		0x00,       // replay md5
+		0x2c, 0x01, // 300s
+		0, 0, 0, 0, 0, 0, 0, 0, 0, 0, // 100s, 50s, gekis, katus, misses
+		0x40, 0x42, 0x0f, 0x00, // score
+		0x00, 0x00, // max combo
+		0x01,                   // perfect
+		0x08, 0x00, 0x00, 0x00, // mods
+		0x00,                                           // life bar
+		0x00, 0x80, 0xb5, 0xf7, 0xf5, 0x7f, 0x9f, 0x08, // timestamp
+		0x02, 0x00, 0x00, 0x00, // data length
+		0xde, 0xad, // data
+		0x2a, 0, 0, 0, 0, 0, 0, 0, // score id
+	}
+	got, err := r.MarshalBinary()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(got, want) {
+		t.Errorf("Replay.MarshalBinary() = %x, want %x", got, want)
+	}
+}
+
+func TestReplay_Hash(t *testing.T) {
+	r := Replay{
+		BeatmapMD5: "a5b99395a42bd55bc5eb1d2411cbdf8b",
+		Username:   "Howl",
+		Count300:   200,
+		Score:      1000000,
+		MaxCombo:   314,
+		Perfect:    true,
+		Mods:       8,
+	}
+	// md5("200p0o0o0t0aa5b99395a42bd55bc5eb1d2411cbdf8br314eTrueyHowlo1000000uSSH8True")
+	want := "65fbe8a043c1872d24e5276220d9c05d"
+	if got := r.Hash("SSH"); got != want {
+		t.Errorf("Replay.Hash() = %v, want %v", got, want)
+	}
+}
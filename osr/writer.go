@@ -0,0 +1,107 @@
+package osr
+
+import (
+	"encoding/binary"
+	"io"
+)
+
+// Writer writes values in the binary format used by osu!, which is to say
+// little endian numbers and strings prefixed by their ULEB128 length.
+// Once an error happens, all subsequent writes are no-ops, and the error is
+// returned by Err.
+type Writer struct {
+	w   io.Writer
+	n   int64
+	err error
+}
+
+// NewWriter creates a new Writer writing to w.
+func NewWriter(w io.Writer) *Writer {
+	return &Writer{w: w}
+}
+
+// Write writes p as it is.
+func (w *Writer) Write(p []byte) (int, error) {
+	if w.err != nil {
+		return 0, w.err
+	}
+	n, err := w.w.Write(p)
+	w.n += int64(n)
+	w.err = err
+	return n, err
+}
+
+// Byte writes a single byte.
+func (w *Writer) Byte(b byte) {
+	w.Write([]byte{b})
+}
+
+// Bool writes a boolean as a single byte.
+func (w *Writer) Bool(b bool) {
+	if b {
+		w.Byte(1)
+		return
+	}
+	w.Byte(0)
+}
+
+// Uint16 writes a 2-byte unsigned integer.
+func (w *Writer) Uint16(i uint16) {
+	var b [2]byte
+	binary.LittleEndian.PutUint16(b[:], i)
+	w.Write(b[:])
+}
+
+// Int32 writes a 4-byte signed integer.
+func (w *Writer) Int32(i int32) {
+	var b [4]byte
+	binary.LittleEndian.PutUint32(b[:], uint32(i))
+	w.Write(b[:])
+}
+
+// Int64 writes an 8-byte signed integer.
+func (w *Writer) Int64(i int64) {
+	var b [8]byte
+	binary.LittleEndian.PutUint64(b[:], uint64(i))
+	w.Write(b[:])
+}
+
+// ULEB128 writes an unsigned integer in the variable-length LEB128 encoding.
+func (w *Writer) ULEB128(i uint64) {
+	var b []byte
+	for {
+		c := byte(i & 0x7f)
+		i >>= 7
+		if i != 0 {
+			c |= 0x80
+		}
+		b = append(b, c)
+		if i == 0 {
+			break
+		}
+	}
+	w.Write(b)
+}
+
+// String writes a string. Empty strings are written as a single 0x00 byte,
+// while the others are written as 0x0b, followed by the length of the string
+// in ULEB128 and by the string itself.
+func (w *Writer) String(s string) {
+	if s == "" {
+		w.Byte(0x00)
+		return
+	}
+	w.Byte(0x0b)
+	w.ULEB128(uint64(len(s)))
+	w.Write([]byte(s))
+}
+
+// N returns the number of bytes written so far.
+func (w *Writer) N() int64 {
+	return w.n
+}
+
+// Err returns the first error that happened while writing, if any.
+func (w *Writer) Err() error {
+	return w.err
+}
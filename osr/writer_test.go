@@ -0,0 +1,45 @@
+package osr
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestWriter_ULEB128(t *testing.T) {
+	tests := []struct {
+		name string
+		i    uint64
+		want []byte
+	}{
+		{"0", 0, []byte{0x00}},
+		{"127", 127, []byte{0x7f}},
+		{"128", 128, []byte{0x80, 0x01}},
+		{"624485", 624485, []byte{0xe5, 0x8e, 0x26}},
+	}
+	for _, tt := range tests {
+		var b bytes.Buffer
+		NewWriter(&b).ULEB128(tt.i)
+		if !bytes.Equal(b.Bytes(), tt.want) {
+			t.Errorf("%q. Writer.ULEB128() = %x, want %x", tt.name, b.Bytes(), tt.want)
+		}
+	}
+}
+
+func TestWriter_String(t *testing.T) {
+	tests := []struct {
+		name string
+		s    string
+		want []byte
+	}{
+		{"empty", "", []byte{0x00}},
+		{"short", "Howl", []byte{0x0b, 0x04, 'H', 'o', 'w', 'l'}},
+		{"long", string(bytes.Repeat([]byte{'a'}, 200)), append([]byte{0x0b, 0xc8, 0x01}, bytes.Repeat([]byte{'a'}, 200)...)},
+	}
+	for _, tt := range tests {
+		var b bytes.Buffer
+		NewWriter(&b).String(tt.s)
+		if !bytes.Equal(b.Bytes(), tt.want) {
+			t.Errorf("%q. Writer.String() = %x, want %x", tt.name, b.Bytes(), tt.want)
+		}
+	}
+}
@@ -0,0 +1,40 @@
+-- Multiplayer matches, as received on the api:mp_complete_match Redis channel.
+-- Used by /api/get_match.
+--
+-- pep.py numbers its matches from 1 again every time it restarts, so matches
+-- have their own ID, and peppy_id is only used to group the games of a match
+-- while it is being played.
+--
+-- pep.py only publishes the games when they are completed, so their start_time
+-- is estimated from the length of the beatmap.
+
+CREATE TABLE IF NOT EXISTS matches (
+	id INT UNSIGNED NOT NULL AUTO_INCREMENT PRIMARY KEY,
+	peppy_id INT UNSIGNED NOT NULL,
+	name VARCHAR(64) NOT NULL DEFAULT '',
+	start_time INT UNSIGNED NOT NULL,
+	end_time INT UNSIGNED NOT NULL,
+	KEY peppy_id (peppy_id, end_time)
+);
+
+CREATE TABLE IF NOT EXISTS match_games (
+	id INT UNSIGNED NOT NULL AUTO_INCREMENT PRIMARY KEY,
+	match_id INT UNSIGNED NOT NULL,
+	beatmap_id INT NOT NULL,
+	play_mode TINYINT NOT NULL,
+	mods INT NOT NULL,
+	start_time INT UNSIGNED NOT NULL,
+	end_time INT UNSIGNED NOT NULL,
+	KEY match_id (match_id)
+);
+
+CREATE TABLE IF NOT EXISTS match_game_scores (
+	game_id INT UNSIGNED NOT NULL,
+	user_id INT NOT NULL,
+	slot TINYINT NOT NULL,
+	team TINYINT NOT NULL,
+	score BIGINT NOT NULL,
+	mods INT NOT NULL,
+	pass TINYINT(1) NOT NULL,
+	PRIMARY KEY (game_id, user_id)
+);
@@ -0,0 +1,15 @@
+-- Beatmap metadata imported from the osu! API by beatmapget, and returned by
+-- /api/get_beatmaps.
+--
+-- The columns have defaults so that the other servers inserting into beatmaps
+-- (lets, pep.py) keep working without knowing about them. TEXT columns can't
+-- have one, so tags is NULL when it is unknown.
+
+ALTER TABLE beatmaps
+	ADD COLUMN creator VARCHAR(32) NOT NULL DEFAULT '',
+	ADD COLUMN tags TEXT NULL,
+	ADD COLUMN genre_id TINYINT NOT NULL DEFAULT 0,
+	ADD COLUMN language_id TINYINT NOT NULL DEFAULT 0,
+	ADD COLUMN source VARCHAR(255) NOT NULL DEFAULT '',
+	ADD COLUMN approved_date INT UNSIGNED NOT NULL DEFAULT 0,
+	ADD KEY creator (creator);
@@ -0,0 +1,18 @@
+-- Daily snapshots of the stats of the users, taken by the API and returned by
+-- /api/v1/users/history. date is the unix timestamp of the start of the day
+-- (UTC) of the snapshot.
+
+CREATE TABLE IF NOT EXISTS users_history (
+	user_id INT NOT NULL,
+	mode TINYINT NOT NULL,
+	special_mode TINYINT NOT NULL,
+	date INT UNSIGNED NOT NULL,
+	pp INT NOT NULL,
+	global_rank INT UNSIGNED NULL,
+	country_rank INT UNSIGNED NULL,
+	ranked_score BIGINT NOT NULL,
+	playcount INT NOT NULL,
+	accuracy FLOAT NOT NULL,
+	PRIMARY KEY (user_id, mode, special_mode, date),
+	KEY date (date)
+);
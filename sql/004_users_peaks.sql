@@ -0,0 +1,16 @@
+-- The best pp and ranks ever reached by the users, with when they reached
+-- them. Kept by the API, and returned by /api/v1/users/full and
+-- /api/v1/users/rxfull.
+
+CREATE TABLE IF NOT EXISTS users_peaks (
+	user_id INT NOT NULL,
+	mode TINYINT NOT NULL,
+	special_mode TINYINT NOT NULL,
+	pp INT NOT NULL,
+	pp_date INT UNSIGNED NOT NULL,
+	global_rank INT UNSIGNED NULL,
+	global_rank_date INT UNSIGNED NULL,
+	country_rank INT UNSIGNED NULL,
+	country_rank_date INT UNSIGNED NULL,
+	PRIMARY KEY (user_id, mode, special_mode)
+);
@@ -0,0 +1,8 @@
+-- Stats of the users in autopilot, with the same columns as rx_stats. Like
+-- for relax, the leaderboards are the ripple:leaderboard_autopilot:* sorted
+-- sets, kept by the score server, and new users need a row in ap_stats as they
+-- do in rx_stats.
+
+CREATE TABLE IF NOT EXISTS ap_stats LIKE rx_stats;
+
+INSERT IGNORE INTO ap_stats (id) SELECT id FROM users;
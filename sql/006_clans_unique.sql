@@ -0,0 +1,11 @@
+-- The names and the tags of the clans, and the invite codes, are unique. The
+-- API checks that they are free before using them, but only these keys stop
+-- two requests from taking the same one at once. With the default collation
+-- the tags are compared case-insensitively, as the API does.
+-- Clans already sharing a name or a tag need to be renamed first.
+
+ALTER TABLE clans
+	ADD UNIQUE KEY name (name),
+	ADD UNIQUE KEY tag (tag);
+
+ALTER TABLE clans_invites ADD UNIQUE KEY invite (invite);
@@ -0,0 +1,5 @@
+-- An user can only be in one clan. The API checks it before adding an user to
+-- a clan, but only this key stops two requests from adding them to two clans
+-- at once. Users already in more than a clan need to leave all but one first.
+
+ALTER TABLE user_clans ADD UNIQUE KEY `user` (`user`);